@@ -8,25 +8,116 @@ import (
 	geom "rasterizer/geometry"
 )
 
-// IndexedTriangleList represents shapes using triangles.
+// Topology describes how the indices of an IndexedTriangleList are assembled
+// into primitives.
+type Topology int
+
+const (
+	// TriangleList uses every three indices as a separate triangle.
+	TriangleList Topology = iota
+	// TriangleStrip forms a triangle from every index and the two before it.
+	TriangleStrip
+	// TriangleFan forms a triangle from every pair of adjacent indices and the
+	// first index.
+	TriangleFan
+	// PointList draws every index as a separate point.
+	PointList
+	// LineList uses every two indices as a separate line.
+	LineList
+	// LineStrip draws a line between every pair of adjacent indices.
+	LineStrip
+)
+
+// IndexedTriangleList represents shapes using triangles. Despite the name, the
+// indices can also describe points or lines, depending on the Topology.
 type IndexedTriangleList struct {
 	Vertices []geom.Vec3
-	Indices  []int
+	// The texture coordinates, normals and linear RGB colors of each vertex, if
+	// the shape has them.
+	TexCoords []geom.Vec2
+	Normals   []geom.Vec3
+	Colors    []geom.Vec3
+	Indices   []int
+	Topology  Topology
+	// Material identifies the surface of the shape when writing to a GBuffer.
+	Material int
+	// If set, triangles facing away from the viewer are drawn instead of
+	// being culled.
+	DoubleSided bool
+}
+
+// Bounds returns the smallest axis-aligned box containing the shape's vertices.
+func (l *IndexedTriangleList) Bounds() geom.AABB {
+	return geom.AABBFromPoints(l.Vertices)
+}
+
+// BoundingSphere returns a sphere containing the shape's vertices.
+func (l *IndexedTriangleList) BoundingSphere() geom.Sphere {
+	return geom.SphereFromPoints(l.Vertices)
+}
+
+// Triangles returns the vertex indices of each triangle of the shape, all with
+// the same winding. Shapes made of points or lines have no triangles.
+func (l *IndexedTriangleList) Triangles() [][3]int {
+	triangles := make([][3]int, 0)
+	switch l.Topology {
+	case TriangleList:
+		for i := 0; i+2 < len(l.Indices); i += 3 {
+			triangles = append(triangles, [3]int{l.Indices[i], l.Indices[i+1], l.Indices[i+2]})
+		}
+	case TriangleStrip:
+		for i := 0; i+2 < len(l.Indices); i++ {
+			// Every other triangle in a strip has its vertices in the opposite order
+			if i%2 == 0 {
+				triangles = append(triangles, [3]int{l.Indices[i], l.Indices[i+1], l.Indices[i+2]})
+			} else {
+				triangles = append(triangles, [3]int{l.Indices[i+1], l.Indices[i], l.Indices[i+2]})
+			}
+		}
+	case TriangleFan:
+		for i := 1; i+1 < len(l.Indices); i++ {
+			triangles = append(triangles, [3]int{l.Indices[0], l.Indices[i], l.Indices[i+1]})
+		}
+	}
+	return triangles
 }
 
 // Canvas is a buffer on which we can draw lines, triangles etc.
 type Canvas struct {
-	image       *image.RGBA
-	depthBuffer [][]float32
+	image *image.RGBA
+	// The depth and stencil buffers are stored row by row, like the image.
+	depthBuffer   []float32
+	stencilBuffer []uint8
+	// Rasterization only draws pixels inside the scissor rectangle.
+	scissor image.Rectangle
+	// If HDR is enabled, colors are stored here unclamped, and only converted
+	// to the RGBA image when the buffer is read.
+	hdr        []geom.Vec3
+	toneMap    ToneMapper
+	exposure   float32
+	fog        *Fog
+	shadowMaps []*ShadowMap
+	// Surfaces with opacity below 1 are blended with what is behind them.
+	opacity          float32
+	transparencyMode TransparencyMode
+	oit              *oitBuffers
+	// If set, attributes are interpolated linearly across the screen, and the
+	// Z-component of vertices holds depth rather than 1/depth.
+	affine bool
 }
 
 // NewCanvas returns a new Canvas with dimensions (width, height).
 func NewCanvas(width, height int) *Canvas {
-	return &Canvas{
-		image: image.NewRGBA(image.Rect(0, 0, width, height)),
-		// Default depth is positive infinity
-		depthBuffer: make2dBuffer(width, height, float32(math.Inf(1))),
+	c := &Canvas{
+		image:         image.NewRGBA(image.Rect(0, 0, width, height)),
+		depthBuffer:   make([]float32, width*height),
+		stencilBuffer: make([]uint8, width*height),
+		scissor:       image.Rect(0, 0, width, height),
+		opacity:       1,
 	}
+	// Default depth is positive infinity
+	c.ClearDepth(float32(math.Inf(1)))
+	return c
 }
 
 // Dimensions returns the width and height of the Canvas.
@@ -35,44 +126,196 @@ func (c *Canvas) Dimensions() (int, int) {
 	return bounds.Max.X, bounds.Max.Y
 }
 
-// Buffer returns the raw RGBA buffer of the Canvas.
+// Buffer returns the raw RGBA buffer of the Canvas. If HDR is enabled, the
+// colors are tone mapped into the buffer first.
 func (c *Canvas) Buffer() []uint8 {
+	if c.hdr != nil {
+		c.resolveHDR()
+	}
 	return c.image.Pix
 }
 
-// Clear resets all the canvas pixels and depth buffer.
+// Clear resets all the canvas pixels to black, the depth buffer to positive
+// infinity and the stencil buffer to zero.
 func (c *Canvas) Clear() {
-	bounds := c.image.Bounds()
-	for i := 0; i < bounds.Max.X; i++ {
-		for j := 0; j < bounds.Max.Y; j++ {
-			c.PutPixel(i, j, color.RGBA{0, 0, 0, 0xFF})
-			c.depthBuffer[j][i] = float32(math.Inf(1))
-		}
+	c.ClearColor(color.RGBA{0, 0, 0, 0xFF})
+	c.ClearDepth(float32(math.Inf(1)))
+	c.ClearStencil(0)
+}
+
+// ClearColor sets every pixel of the canvas to clr.
+func (c *Canvas) ClearColor(clr color.Color) {
+	if c.hdr != nil {
+		fillVec3(c.hdr, DecodeSRGB(clr))
+		return
+	}
+
+	rgba := color.RGBAModel.Convert(clr).(color.RGBA)
+	pix := c.image.Pix
+	if len(pix) == 0 {
+		return
+	}
+	pix[0], pix[1], pix[2], pix[3] = rgba.R, rgba.G, rgba.B, rgba.A
+	// Fill the rest by repeatedly doubling the filled part
+	for i := 4; i < len(pix); i *= 2 {
+		copy(pix[i:], pix[:i])
 	}
 }
 
+// ClearDepth sets the depth of every pixel of the canvas.
+func (c *Canvas) ClearDepth(depth float32) {
+	if len(c.depthBuffer) == 0 {
+		return
+	}
+	c.depthBuffer[0] = depth
+	for i := 1; i < len(c.depthBuffer); i *= 2 {
+		copy(c.depthBuffer[i:], c.depthBuffer[:i])
+	}
+}
+
+// ClearStencil sets the stencil value of every pixel of the canvas.
+func (c *Canvas) ClearStencil(value uint8) {
+	if len(c.stencilBuffer) == 0 {
+		return
+	}
+	c.stencilBuffer[0] = value
+	for i := 1; i < len(c.stencilBuffer); i *= 2 {
+		copy(c.stencilBuffer[i:], c.stencilBuffer[:i])
+	}
+}
+
+// StencilAt returns the stencil value of the pixel at (x, y).
+func (c *Canvas) StencilAt(x, y int) uint8 {
+	return c.stencilBuffer[c.index(x, y)]
+}
+
+// SetStencil sets the stencil value of the pixel at (x, y).
+func (c *Canvas) SetStencil(x, y int, value uint8) {
+	if point := (image.Point{X: x, Y: y}); point.In(c.image.Bounds()) {
+		c.stencilBuffer[c.index(x, y)] = value
+	}
+}
+
+// index returns the position of the pixel at (x, y) in the depth and stencil
+// buffers.
+func (c *Canvas) index(x, y int) int {
+	return y*c.image.Bounds().Dx() + x
+}
+
+// SetScissor restricts drawing to pixels inside r. This applies to
+// rasterization, PutPixel and the 2D drawing functions, but not to
+// PutPixelHDR, which post-processing uses to rewrite the whole Canvas.
+func (c *Canvas) SetScissor(r image.Rectangle) {
+	c.scissor = r.Intersect(c.image.Bounds())
+}
+
+// ResetScissor allows drawing anywhere on the Canvas.
+func (c *Canvas) ResetScissor() {
+	c.scissor = c.image.Bounds()
+}
+
+// Scissor returns the rectangle that drawing is restricted to.
+func (c *Canvas) Scissor() image.Rectangle {
+	return c.scissor
+}
+
 // PutPixel puts at pixel at (x, y) on the Canvas, with (0, 0) as the top-left corner.
-func (c *Canvas) PutPixel(x, y int, color color.Color) {
-	c.image.Set(x, y, color)
+// The color is assumed to be in sRGB, like the colors of an image. Pixels
+// outside the scissor rectangle are left unchanged.
+func (c *Canvas) PutPixel(x, y int, clr color.Color) {
+	if point := (image.Point{X: x, Y: y}); !point.In(c.scissor) {
+		return
+	}
+	if c.hdr != nil {
+		c.PutPixelHDR(x, y, DecodeSRGB(clr))
+		return
+	}
+	c.image.Set(x, y, clr)
+}
+
+// DepthAt returns the depth of the pixel at (x, y), which is positive infinity
+// if nothing has been drawn there.
+func (c *Canvas) DepthAt(x, y int) float32 {
+	return c.depthBuffer[c.index(x, y)]
 }
 
 // TestAndSet sets the depth value at (x, y) if it is smallest than the existing,
-// and returns whether the depth was set.
+// and returns whether the depth was set. Pixels outside the scissor rectangle
+// are never set.
 func (c *Canvas) TestAndSet(x, y int, depth float32) bool {
-	if point := (image.Point{X: x, Y: y}); !point.In(c.image.Bounds()) {
+	if point := (image.Point{X: x, Y: y}); !point.In(c.scissor) {
 		return false
 	}
 
-	if depth < c.depthBuffer[y][x] {
-		c.depthBuffer[y][x] = depth
+	if i := c.index(x, y); depth < c.depthBuffer[i] {
+		c.depthBuffer[i] = depth
 		return true
 	}
 	return false
 }
 
+// SetPerspectiveCorrect sets whether vertices are interpolated with perspective,
+// which is the default. Vertices drawn with perspective must have their
+// attributes divided by their depth, and hold 1/depth in the Z-component of
+// their position. Otherwise, as for orthographic projections, they are used as
+// they are.
+func (c *Canvas) SetPerspectiveCorrect(enabled bool) {
+	c.affine = !enabled
+}
+
 // FillTriangle fills the triangle formed by the given three points with the
 // specified color, using the top-left rule.
 func (c *Canvas) FillTriangle(v0, v1, v2 TexVertex, tex Texture) {
+	rasterizeTriangle(v0, v1, v2, !c.affine, c.shader(tex))
+}
+
+// FillLine draws a line between the two given points, testing each pixel against
+// the depth buffer like FillTriangle.
+func (c *Canvas) FillLine(v0, v1 TexVertex, tex Texture) {
+	rasterizeLine(v0, v1, !c.affine, c.shader(tex))
+}
+
+// FillPoint draws a square sprite of the given size in pixels, centered on v.
+// The sprite is shaded with texture coordinates going from 0 to 1 across it.
+func (c *Canvas) FillPoint(v TexVertex, size float32, tex Texture) {
+	rasterizePoint(v, size, !c.affine, c.shader(tex))
+}
+
+// shader returns a pixelFunc that shades pixels using tex.
+func (c *Canvas) shader(tex Texture) pixelFunc {
+	if c.opacity < 1 {
+		return c.transparentShader(tex)
+	}
+
+	return func(x, y int, v TexVertex) {
+		// We test the pixel to be drawn against the depth buffer; we only want to draw it
+		// if it will be on top of anything already present.
+		if c.TestAndSet(x, y, v.Pos.Z) {
+			c.PutPixelHDR(x, y, c.shadePixel(tex, v))
+		}
+	}
+}
+
+// shadePixel returns the linear color of a pixel, applying shadows and fog.
+func (c *Canvas) shadePixel(tex Texture, v TexVertex) geom.Vec3 {
+	clr := tex.shade(v)
+	if len(c.shadowMaps) > 0 {
+		clr = clr.Scale(c.shadowFactor(v.WorldPos))
+	}
+	if c.fog != nil {
+		clr = c.fog.apply(clr, v.Pos.Z, v.WorldPos)
+	}
+	return clr
+}
+
+// pixelFunc is called by the rasterizer for every pixel covered by a triangle.
+// The vertex has its attributes perspective-corrected, and the depth in Pos.Z.
+type pixelFunc func(x, y int, v TexVertex)
+
+// rasterizeTriangle calls plot for every pixel covered by the triangle formed
+// by the given three points, using the top-left rule. If perspective is set,
+// the vertices hold 1/Z and attributes divided by Z.
+func rasterizeTriangle(v0, v1, v2 TexVertex, perspective bool, plot pixelFunc) {
 	// Sort points by their Y-coordinate
 	if v1.Pos.Y < v0.Pos.Y {
 		v0, v1 = v1, v0
@@ -87,19 +330,19 @@ func (c *Canvas) FillTriangle(v0, v1, v2 TexVertex, tex Texture) {
 
 	switch {
 	case vTop.Pos.Y == vMid.Pos.Y:
-		c.fillTriangleFlatTop(vTop, vMid, vBottom, tex)
+		fillTriangleFlatTop(vTop, vMid, vBottom, perspective, plot)
 	case vMid.Pos.Y == vBottom.Pos.Y:
-		c.fillTriangleFlatBottom(vTop, vMid, vBottom, tex)
+		fillTriangleFlatBottom(vTop, vMid, vBottom, perspective, plot)
 	default:
 		alpha := (vMid.Pos.Y - vTop.Pos.Y) / (vBottom.Pos.Y - vTop.Pos.Y)
 		vSplit := vTop.InterpolateTo(vBottom, alpha)
 
-		c.fillTriangleFlatBottom(vTop, vMid, vSplit, tex)
-		c.fillTriangleFlatTop(vMid, vSplit, vBottom, tex)
+		fillTriangleFlatBottom(vTop, vMid, vSplit, perspective, plot)
+		fillTriangleFlatTop(vMid, vSplit, vBottom, perspective, plot)
 	}
 }
 
-func (c *Canvas) fillTriangleFlatTop(vLeft, vRight, vBottom TexVertex, tex Texture) {
+func fillTriangleFlatTop(vLeft, vRight, vBottom TexVertex, perspective bool, plot pixelFunc) {
 	if vRight.Pos.X < vLeft.Pos.X {
 		vLeft, vRight = vRight, vLeft
 	}
@@ -111,10 +354,10 @@ func (c *Canvas) fillTriangleFlatTop(vLeft, vRight, vBottom TexVertex, tex Textu
 	// Round half down to follow the top-left rule
 	yStart, yEnd := int(roundHalfDown(vLeft.Pos.Y)), int(roundHalfDown(vBottom.Pos.Y))
 
-	c.fillTriangleFlat(vLeft, vRight, stepLeft, stepRight, yStart, yEnd, tex)
+	fillTriangleFlat(vLeft, vRight, stepLeft, stepRight, yStart, yEnd, perspective, plot)
 }
 
-func (c *Canvas) fillTriangleFlatBottom(vTop, vLeft, vRight TexVertex, tex Texture) {
+func fillTriangleFlatBottom(vTop, vLeft, vRight TexVertex, perspective bool, plot pixelFunc) {
 	if vRight.Pos.X < vLeft.Pos.X {
 		vLeft, vRight = vRight, vLeft
 	}
@@ -126,13 +369,14 @@ func (c *Canvas) fillTriangleFlatBottom(vTop, vLeft, vRight TexVertex, tex Textu
 	// Round half down to follow the top-left rule
 	yStart, yEnd := int(roundHalfDown(vTop.Pos.Y)), int(roundHalfDown(vLeft.Pos.Y))
 
-	c.fillTriangleFlat(vLeft, vRight, stepLeft, stepRight, yStart, yEnd, tex)
+	fillTriangleFlat(vLeft, vRight, stepLeft, stepRight, yStart, yEnd, perspective, plot)
 }
 
-func (c *Canvas) fillTriangleFlat(
+func fillTriangleFlat(
 	vLeft, vRight, stepLeft, stepRight TexVertex,
 	yStart, yEnd int,
-	tex Texture) {
+	perspective bool,
+	plot pixelFunc) {
 	// Add 0.5 because we want to use the midpoint of the pixel
 	scanLeft := vLeft.Add(stepLeft.Scale(float32(yStart) + 0.5 - vLeft.Pos.Y))
 	scanRight := vRight.Add(stepRight.Scale(float32(yStart) + 0.5 - vRight.Pos.Y))
@@ -146,16 +390,7 @@ func (c *Canvas) fillTriangleFlat(
 		scanCoord := scanLeft.Add(step.Scale(float32(xStart) + 0.5 - scanLeft.Pos.X))
 
 		for x := xStart; x < xEnd; x++ {
-			// We stored 1/Z in the Z-component so that interpolation will preserve
-			// depth perspective. We need to undo the multiplication to get the original
-			// texture coordinates.
-			depth := 1 / scanCoord.Pos.Z
-
-			// We test the pixel to be drawn against the depth buffer; we only want to draw it
-			// if it will be on top of anything already present.
-			if c.TestAndSet(x, y, depth) {
-				c.PutPixel(x, y, tex.shade(scanCoord.Scale(depth)))
-			}
+			plot(x, y, resolveVertex(scanCoord, perspective))
 			scanCoord = scanCoord.Add(step)
 		}
 
@@ -164,6 +399,66 @@ func (c *Canvas) fillTriangleFlat(
 	}
 }
 
+// rasterizeLine calls plot for every pixel along the line between the two
+// given points.
+func rasterizeLine(v0, v1 TexVertex, perspective bool, plot pixelFunc) {
+	dx, dy := v1.Pos.X-v0.Pos.X, v1.Pos.Y-v0.Pos.Y
+	steps := int(math.Ceil(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy)))))
+	if steps == 0 {
+		steps = 1
+	}
+
+	step := v1.Sub(v0).Scale(1 / float32(steps))
+	coord := v0
+	for i := 0; i <= steps; i++ {
+		// Round down, so that points just left of or above the canvas are not
+		// moved onto its first column or row
+		x, y := int(math.Floor(float64(coord.Pos.X))), int(math.Floor(float64(coord.Pos.Y)))
+		plot(x, y, resolveVertex(coord, perspective))
+		coord = coord.Add(step)
+	}
+}
+
+// rasterizePoint calls plot for every pixel in a square of the given size
+// centered on v.
+func rasterizePoint(v TexVertex, size float32, perspective bool, plot pixelFunc) {
+	if size < 1 {
+		size = 1
+	}
+
+	center := resolveVertex(v, perspective)
+
+	xStart := int(roundHalfDown(v.Pos.X - size/2))
+	yStart := int(roundHalfDown(v.Pos.Y - size/2))
+	xEnd := int(roundHalfDown(v.Pos.X + size/2))
+	yEnd := int(roundHalfDown(v.Pos.Y + size/2))
+
+	for y := yStart; y < yEnd; y++ {
+		for x := xStart; x < xEnd; x++ {
+			sprite := center
+			sprite.TexPos = geom.Vec2{
+				X: (float32(x) + 0.5 - (v.Pos.X - size/2)) / size,
+				Y: (float32(y) + 0.5 - (v.Pos.Y - size/2)) / size,
+			}
+			plot(x, y, sprite)
+		}
+	}
+}
+
+// resolveVertex returns the attributes of an interpolated vertex. With
+// perspective, we stored 1/Z in the Z-component so that interpolation will
+// preserve depth perspective, and we need to undo the multiplication to get the
+// original texture coordinates.
+func resolveVertex(v TexVertex, perspective bool) TexVertex {
+	if !perspective {
+		return v
+	}
+	depth := 1 / v.Pos.Z
+	resolved := v.Scale(depth)
+	resolved.Pos.Z = depth
+	return resolved
+}
+
 // roundHalfDown rounds x to the nearest integer, but 0.5 is rounded down.
 func roundHalfDown(x float32) float32 {
 	return float32(math.Ceil(float64(x) - 0.5))
@@ -213,14 +508,3 @@ func interpolateVertical(p0, p1 geom.Vec2) []geom.Vec2 {
 	}
 	return verts
 }
-
-func make2dBuffer(width, height int, val float32) [][]float32 {
-	buffer := make([][]float32, height)
-	for j := 0; j < len(buffer); j++ {
-		buffer[j] = make([]float32, width)
-		for i := 0; i < len(buffer); i++ {
-			buffer[j][i] = val
-		}
-	}
-	return buffer
-}
@@ -0,0 +1,34 @@
+package canvas
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	geom "rasterizer/geometry"
+)
+
+func TestRasterizeLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		v0, v1 geom.Vec2
+		want   []image.Point
+	}{
+		{"positive", geom.Vec2{X: 0.5, Y: 1.5}, geom.Vec2{X: 2.5, Y: 1.5}, []image.Point{{0, 1}, {1, 1}, {2, 1}}},
+		// Negative coordinates round down rather than towards zero, so no pixel
+		// is plotted twice
+		{"negative X", geom.Vec2{X: -1.5, Y: 0.5}, geom.Vec2{X: 0.5, Y: 0.5}, []image.Point{{-2, 0}, {-1, 0}, {0, 0}}},
+		{"negative Y", geom.Vec2{X: 0.5, Y: -0.5}, geom.Vec2{X: 0.5, Y: 1.5}, []image.Point{{0, -1}, {0, 0}, {0, 1}}},
+	}
+	for _, tt := range tests {
+		var got []image.Point
+		v0 := TexVertex{Pos: geom.Vec3{X: tt.v0.X, Y: tt.v0.Y, Z: 1}}
+		v1 := TexVertex{Pos: geom.Vec3{X: tt.v1.X, Y: tt.v1.Y, Z: 1}}
+		rasterizeLine(v0, v1, false, func(x, y int, v TexVertex) {
+			got = append(got, image.Point{X: x, Y: y})
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: plotted %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	geom "rasterizer/geometry"
+)
+
+// FillRule determines which parts of a self-intersecting path are inside it.
+type FillRule int
+
+const (
+	// NonZero fills points that the path winds around a non-zero number of times.
+	NonZero FillRule = iota
+	// EvenOdd fills points that are enclosed by an odd number of edges.
+	EvenOdd
+)
+
+// Each row of pixels is sampled this many times to anti-alias the edges of
+// shapes. Horizontal coverage is computed exactly.
+const subsamples = 4
+
+// FillPath fills the inside of the path with clr, anti-aliasing its edges.
+// Every subpath is treated as closed. The color's alpha is respected.
+func (c *Canvas) FillPath(path *Path, clr color.Color, rule FillRule) {
+	edges := make([][2]geom.Vec2, 0)
+	for _, sub := range path.subpaths {
+		for i := range sub {
+			edges = append(edges, [2]geom.Vec2{sub[i], sub[(i+1)%len(sub)]})
+		}
+	}
+	c.fillEdges(edges, clr, rule)
+}
+
+// StrokePath draws the outline of the path with lines of the given width,
+// anti-aliasing their edges. Lines are joined with rounded corners.
+func (c *Canvas) StrokePath(path *Path, width float32, clr color.Color) {
+	outline := &Path{}
+	for i, sub := range path.subpaths {
+		points := sub
+		closed := path.closed[i] && len(sub) > 1
+		if closed {
+			points = append(points[:len(points):len(points)], sub[0])
+		}
+		strokePolyline(outline, points, width/2, closed)
+	}
+
+	// Every piece of the outline winds the same way, so the non-zero rule fills
+	// the union of the pieces even where they overlap.
+	c.FillPath(outline, clr, NonZero)
+}
+
+// FillRect fills the rectangle with top-left corner (x, y).
+func (c *Canvas) FillRect(x, y, width, height float32, clr color.Color) {
+	path := &Path{}
+	path.Rect(x, y, width, height)
+	c.FillPath(path, clr, NonZero)
+}
+
+// StrokeRect draws the outline of the rectangle with top-left corner (x, y).
+func (c *Canvas) StrokeRect(x, y, width, height, lineWidth float32, clr color.Color) {
+	path := &Path{}
+	path.Rect(x, y, width, height)
+	c.StrokePath(path, lineWidth, clr)
+}
+
+// FillEllipse fills the ellipse around center. Use equal radii for a circle.
+func (c *Canvas) FillEllipse(center geom.Vec2, radiusX, radiusY float32, clr color.Color) {
+	path := &Path{}
+	path.Ellipse(center, radiusX, radiusY)
+	c.FillPath(path, clr, NonZero)
+}
+
+// StrokeEllipse draws the outline of the ellipse around center.
+func (c *Canvas) StrokeEllipse(center geom.Vec2, radiusX, radiusY, lineWidth float32, clr color.Color) {
+	path := &Path{}
+	path.Ellipse(center, radiusX, radiusY)
+	c.StrokePath(path, lineWidth, clr)
+}
+
+// StrokeArc draws a circular arc around center, from angle start to angle end
+// in radians, measured clockwise from the positive X-axis.
+func (c *Canvas) StrokeArc(center geom.Vec2, radius, start, end, lineWidth float32, clr color.Color) {
+	path := &Path{}
+	path.subpaths = append(path.subpaths, nil)
+	path.closed = append(path.closed, false)
+	path.Arc(center, radius, radius, start, end)
+	c.StrokePath(path, lineWidth, clr)
+}
+
+// FillPolygon fills the polygon through the given points, which may be concave
+// or self-intersecting.
+func (c *Canvas) FillPolygon(points []geom.Vec2, clr color.Color, rule FillRule) {
+	path := &Path{}
+	path.Polygon(points)
+	c.FillPath(path, clr, rule)
+}
+
+// strokePolyline adds the outline of a line of the given half-width through the
+// points to the path, as a quadrilateral for every segment and a circle for
+// every joint. If the line is closed, its last point repeats the first, and
+// that point is a joint too.
+func strokePolyline(outline *Path, points []geom.Vec2, halfWidth float32, closed bool) {
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		length := a.Distance(b)
+		if length == 0 {
+			continue
+		}
+
+		// Offset perpendicular to the segment
+		normal := geom.Vec2{X: -(b.Y - a.Y), Y: b.X - a.X}.Scale(halfWidth / length)
+		outline.Polygon(orientPolygon([]geom.Vec2{
+			a.Add(normal), b.Add(normal), b.Sub(normal), a.Sub(normal),
+		}))
+	}
+
+	first := 1
+	if closed {
+		first = 0
+	}
+	for i := first; i+1 < len(points); i++ {
+		joint := &Path{}
+		joint.Ellipse(points[i], halfWidth, halfWidth)
+		outline.Polygon(orientPolygon(joint.subpaths[0]))
+	}
+}
+
+// orientPolygon returns the polygon with its vertices in clockwise order on the
+// screen, reversing them if necessary.
+func orientPolygon(points []geom.Vec2) []geom.Vec2 {
+	var area float32
+	for i := range points {
+		p, q := points[i], points[(i+1)%len(points)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	if area < 0 {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	return points
+}
+
+// crossing is where an edge crosses a row of samples, and which way it goes.
+type crossing struct {
+	x       float32
+	winding int
+}
+
+// fillEdges fills the area enclosed by the edges according to the fill rule.
+// Each row of pixels accumulates coverage from several sub-rows, and each pixel
+// is then blended with clr in proportion to its coverage.
+func (c *Canvas) fillEdges(edges [][2]geom.Vec2, clr color.Color, rule FillRule) {
+	if len(edges) == 0 {
+		return
+	}
+
+	minY, maxY := float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, e := range edges {
+		minY = float32(math.Min(float64(minY), math.Min(float64(e[0].Y), float64(e[1].Y))))
+		maxY = float32(math.Max(float64(maxY), math.Max(float64(e[0].Y), float64(e[1].Y))))
+	}
+
+	bounds := c.scissor
+	yStart := maxInt(int(math.Floor(float64(minY))), bounds.Min.Y)
+	yEnd := minInt(int(math.Ceil(float64(maxY))), bounds.Max.Y)
+
+	src := DecodeSRGB(clr)
+	_, _, _, a := clr.RGBA()
+	alpha := float32(a) / 0xFFFF
+
+	coverage := make([]float32, bounds.Dx())
+	crossings := make([]crossing, 0)
+
+	for y := yStart; y < yEnd; y++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+
+		for s := 0; s < subsamples; s++ {
+			sampleY := float32(y) + (float32(s)+0.5)/subsamples
+
+			crossings = crossings[:0]
+			for _, e := range edges {
+				p0, p1 := e[0], e[1]
+				winding := 1
+				if p1.Y < p0.Y {
+					p0, p1 = p1, p0
+					winding = -1
+				}
+				// Half-open so that a vertex shared by two edges is counted once
+				if sampleY < p0.Y || sampleY >= p1.Y {
+					continue
+				}
+				x := p0.X + (sampleY-p0.Y)/(p1.Y-p0.Y)*(p1.X-p0.X)
+				crossings = append(crossings, crossing{x: x, winding: winding})
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i := 0; i+1 < len(crossings); i++ {
+				winding += crossings[i].winding
+				inside := winding != 0
+				if rule == EvenOdd {
+					inside = (i+1)%2 == 1
+				}
+				if inside {
+					addSpan(coverage, crossings[i].x-float32(bounds.Min.X), crossings[i+1].x-float32(bounds.Min.X))
+				}
+			}
+		}
+
+		for i, cover := range coverage {
+			if cover <= 0 {
+				continue
+			}
+			c.blendPixel(bounds.Min.X+i, y, src, alpha*float32(math.Min(float64(cover), 1)))
+		}
+	}
+}
+
+// blendPixel blends the linear color src over the pixel at (x, y) by the given
+// amount, unless the pixel is outside the scissor rectangle.
+func (c *Canvas) blendPixel(x, y int, src geom.Vec3, amount float32) {
+	if point := (image.Point{X: x, Y: y}); !point.In(c.scissor) {
+		return
+	}
+	c.PutPixelHDR(x, y, c.ColorAt(x, y).InterpolateTo(src, amount))
+}
+
+// addSpan adds the coverage of one sub-row between x0 and x1 to each pixel,
+// including partial coverage of the pixels at either end.
+func addSpan(coverage []float32, x0, x1 float32) {
+	x0 = float32(math.Max(float64(x0), 0))
+	x1 = float32(math.Min(float64(x1), float64(len(coverage))))
+	if x1 <= x0 {
+		return
+	}
+
+	for i := int(x0); i < len(coverage) && float32(i) < x1; i++ {
+		overlap := math.Min(float64(x1), float64(i+1)) - math.Max(float64(x0), float64(i))
+		coverage[i] += float32(overlap) / subsamples
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
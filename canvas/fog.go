@@ -0,0 +1,70 @@
+package canvas
+
+import (
+	"math"
+
+	geom "rasterizer/geometry"
+)
+
+// FogMode determines how the amount of fog grows with depth.
+type FogMode int
+
+const (
+	// FogLinear increases linearly from Start to End.
+	FogLinear FogMode = iota
+	// FogExponential increases exponentially with depth, scaled by Density.
+	FogExponential
+	// FogExponentialSquared increases exponentially with the square of depth,
+	// so it stays thin near the viewer and thickens sharply further away.
+	FogExponentialSquared
+)
+
+// Fog blends surfaces towards a color the further away they are.
+type Fog struct {
+	Mode FogMode
+	// Color is the linear RGB color of the fog.
+	Color geom.Vec3
+	// Start and End are the depths between which linear fog increases. If End
+	// is not beyond Start, surfaces beyond Start are hidden by fog completely.
+	Start, End float32
+	// Density scales exponential fog.
+	Density float32
+	// If HeightFalloff is positive, the fog thins out exponentially above
+	// HeightBase, and thickens below it.
+	HeightBase    float32
+	HeightFalloff float32
+}
+
+// SetFog applies fog to everything subsequently drawn on the Canvas. Passing
+// nil removes the fog.
+func (c *Canvas) SetFog(fog *Fog) {
+	c.fog = fog
+}
+
+// apply blends clr with the fog color for a surface at pos with the given depth.
+func (f *Fog) apply(clr geom.Vec3, depth float32, pos geom.Vec3) geom.Vec3 {
+	height := float32(1)
+	if f.HeightFalloff > 0 {
+		height = float32(math.Exp(float64(-f.HeightFalloff * (pos.Y - f.HeightBase))))
+	}
+
+	var visibility float32
+	switch f.Mode {
+	case FogLinear:
+		var amount float32
+		switch {
+		case f.End > f.Start:
+			amount = clamp01((depth - f.Start) / (f.End - f.Start))
+		case depth > f.Start:
+			amount = 1
+		}
+		visibility = 1 - clamp01(amount*height)
+	case FogExponential:
+		visibility = float32(math.Exp(float64(-f.Density * height * depth)))
+	case FogExponentialSquared:
+		d := f.Density * depth
+		visibility = float32(math.Exp(float64(-d * d * height)))
+	}
+
+	return f.Color.InterpolateTo(clr, visibility)
+}
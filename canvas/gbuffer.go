@@ -0,0 +1,143 @@
+package canvas
+
+import (
+	"image"
+	"math"
+
+	geom "rasterizer/geometry"
+)
+
+// GBuffer stores several per-pixel surface attributes instead of a final color,
+// so that lighting can be computed later in a separate pass.
+type GBuffer struct {
+	width, height int
+	albedo        []geom.Vec3
+	normal        []geom.Vec3
+	position      []geom.Vec3
+	depth         []float32
+	material      []int
+	// See Canvas.SetPerspectiveCorrect.
+	affine bool
+	// Surfaces are only written to pixels inside the scissor rectangle.
+	scissor image.Rectangle
+}
+
+// Surface contains the attributes stored in a GBuffer for a single pixel.
+type Surface struct {
+	// Albedo is the linear RGB color of the surface.
+	Albedo   geom.Vec3
+	Normal   geom.Vec3
+	Position geom.Vec3
+	Depth    float32
+	Material int
+}
+
+// NewGBuffer returns a new GBuffer with dimensions (width, height).
+func NewGBuffer(width, height int) *GBuffer {
+	size := width * height
+	g := &GBuffer{
+		width:    width,
+		height:   height,
+		albedo:   make([]geom.Vec3, size),
+		normal:   make([]geom.Vec3, size),
+		position: make([]geom.Vec3, size),
+		depth:    make([]float32, size),
+		material: make([]int, size),
+		scissor:  image.Rect(0, 0, width, height),
+	}
+	g.Clear()
+	return g
+}
+
+// Dimensions returns the width and height of the GBuffer.
+func (g *GBuffer) Dimensions() (int, int) {
+	return g.width, g.height
+}
+
+// Clear resets every pixel so that it has no surface.
+func (g *GBuffer) Clear() {
+	for i := range g.depth {
+		g.albedo[i] = geom.Vec3{}
+		g.normal[i] = geom.Vec3{}
+		g.position[i] = geom.Vec3{}
+		// Default depth is positive infinity
+		g.depth[i] = float32(math.Inf(1))
+		g.material[i] = 0
+	}
+}
+
+// At returns the surface attributes stored at (x, y).
+func (g *GBuffer) At(x, y int) Surface {
+	i := y*g.width + x
+	return Surface{
+		Albedo:   g.albedo[i],
+		Normal:   g.normal[i],
+		Position: g.position[i],
+		Depth:    g.depth[i],
+		Material: g.material[i],
+	}
+}
+
+// Covered returns whether any surface has been written at (x, y).
+func (g *GBuffer) Covered(x, y int) bool {
+	return !math.IsInf(float64(g.depth[y*g.width+x]), 1)
+}
+
+// SetScissor restricts writing surfaces to pixels inside r.
+func (g *GBuffer) SetScissor(r image.Rectangle) {
+	g.scissor = r.Intersect(image.Rect(0, 0, g.width, g.height))
+}
+
+// ResetScissor allows surfaces to be written anywhere in the GBuffer.
+func (g *GBuffer) ResetScissor() {
+	g.scissor = image.Rect(0, 0, g.width, g.height)
+}
+
+// Scissor returns the rectangle that writing surfaces is restricted to.
+func (g *GBuffer) Scissor() image.Rectangle {
+	return g.scissor
+}
+
+// SetPerspectiveCorrect sets whether vertices are interpolated with
+// perspective, as for Canvas.SetPerspectiveCorrect.
+func (g *GBuffer) SetPerspectiveCorrect(enabled bool) {
+	g.affine = !enabled
+}
+
+// FillTriangle writes the surface attributes of the triangle formed by the
+// given three points, keeping only the surfaces closest to the viewer.
+func (g *GBuffer) FillTriangle(v0, v1, v2 TexVertex, tex Texture, material int) {
+	rasterizeTriangle(v0, v1, v2, !g.affine, g.writer(tex, material))
+}
+
+// FillLine writes the surface attributes along the line between the two given
+// points.
+func (g *GBuffer) FillLine(v0, v1 TexVertex, tex Texture, material int) {
+	rasterizeLine(v0, v1, !g.affine, g.writer(tex, material))
+}
+
+// FillPoint writes the surface attributes of a square sprite of the given size
+// in pixels, centered on v.
+func (g *GBuffer) FillPoint(v TexVertex, size float32, tex Texture, material int) {
+	rasterizePoint(v, size, !g.affine, g.writer(tex, material))
+}
+
+// writer returns a pixelFunc that writes surface attributes to the GBuffer.
+func (g *GBuffer) writer(tex Texture, material int) pixelFunc {
+	return func(x, y int, v TexVertex) {
+		if point := (image.Point{X: x, Y: y}); !point.In(g.scissor) {
+			return
+		}
+
+		i := y*g.width + x
+		if v.Pos.Z >= g.depth[i] {
+			return
+		}
+
+		g.albedo[i] = tex.shade(v)
+		g.normal[i] = v.Normal.Normalize()
+		g.position[i] = v.WorldPos
+		g.depth[i] = v.Pos.Z
+		g.material[i] = material
+	}
+}
@@ -0,0 +1,111 @@
+package canvas
+
+import (
+	"image"
+
+	geom "rasterizer/geometry"
+)
+
+// ToneMapper maps an unbounded RGB color to one with components between 0 and 1.
+type ToneMapper func(clr geom.Vec3) geom.Vec3
+
+// EnableHDR makes the Canvas store colors as floating-point values that may
+// exceed 1. When the buffer is read, colors are scaled by exposure and then
+// converted using toneMap, or ToneMapClamp if it is nil. Anything already drawn
+// is kept, decoded from the RGBA buffer.
+func (c *Canvas) EnableHDR(toneMap ToneMapper, exposure float32) {
+	if toneMap == nil {
+		toneMap = ToneMapClamp
+	}
+	w, h := c.Dimensions()
+	if c.hdr == nil {
+		c.hdr = make([]geom.Vec3, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c.hdr[y*w+x] = DecodeSRGB(c.image.RGBAAt(x, y))
+			}
+		}
+	}
+	c.toneMap = toneMap
+	c.exposure = exposure
+}
+
+// DisableHDR makes the Canvas store colors directly in the RGBA buffer again.
+func (c *Canvas) DisableHDR() {
+	c.hdr = nil
+}
+
+// PutPixelHDR puts a pixel at (x, y) with a linear RGB color, whose components
+// may exceed 1. If HDR is not enabled, the components are clamped and the color
+// is encoded to sRGB.
+func (c *Canvas) PutPixelHDR(x, y int, clr geom.Vec3) {
+	if point := (image.Point{X: x, Y: y}); !point.In(c.image.Bounds()) {
+		return
+	}
+
+	if c.hdr == nil {
+		c.image.SetRGBA(x, y, EncodeSRGB(clr))
+		return
+	}
+	w, _ := c.Dimensions()
+	c.hdr[y*w+x] = clr
+}
+
+// ColorAt returns the linear RGB color of the pixel at (x, y). If HDR is
+// enabled, the components may exceed 1.
+func (c *Canvas) ColorAt(x, y int) geom.Vec3 {
+	if c.hdr != nil {
+		w, _ := c.Dimensions()
+		return c.hdr[y*w+x]
+	}
+	return DecodeSRGB(c.image.RGBAAt(x, y))
+}
+
+// resolveHDR tone maps the HDR colors into the RGBA buffer, encoded to sRGB.
+func (c *Canvas) resolveHDR() {
+	w, h := c.Dimensions()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			clr := c.hdr[y*w+x].Scale(c.exposure)
+			c.image.SetRGBA(x, y, EncodeSRGB(c.toneMap(clr)))
+		}
+	}
+}
+
+// ToneMapClamp clamps each component, so any value above 1 saturates.
+func ToneMapClamp(clr geom.Vec3) geom.Vec3 {
+	return clr
+}
+
+// ToneMapReinhard applies the Reinhard operator x / (1 + x) to each component.
+func ToneMapReinhard(clr geom.Vec3) geom.Vec3 {
+	return geom.Vec3{
+		X: clr.X / (1 + clr.X),
+		Y: clr.Y / (1 + clr.Y),
+		Z: clr.Z / (1 + clr.Z),
+	}
+}
+
+// ToneMapACES applies Narkowicz's fit of the ACES filmic curve to each component.
+func ToneMapACES(clr geom.Vec3) geom.Vec3 {
+	return geom.Vec3{
+		X: acesFilmic(clr.X),
+		Y: acesFilmic(clr.Y),
+		Z: acesFilmic(clr.Z),
+	}
+}
+
+func acesFilmic(x float32) float32 {
+	const a, b, c, d, e = 2.51, 0.03, 2.43, 0.59, 0.14
+	return (x * (a*x + b)) / (x*(c*x+d) + e)
+}
+
+func fillVec3(buf []geom.Vec3, v geom.Vec3) {
+	if len(buf) == 0 {
+		return
+	}
+	buf[0] = v
+	for i := 1; i < len(buf); i *= 2 {
+		copy(buf[i:], buf[:i])
+	}
+}
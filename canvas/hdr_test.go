@@ -0,0 +1,19 @@
+package canvas
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestEnableHDRKeepsPixels(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.PutPixel(0, 0, color.RGBA{R: 0xFF, G: 0x80, A: 0xFF})
+	c.PutPixel(1, 0, color.RGBA{B: 0x40, A: 0xFF})
+	want := append([]uint8(nil), c.Buffer()...)
+
+	c.EnableHDR(nil, 1)
+	if got := c.Buffer(); !bytes.Equal(got, want) {
+		t.Errorf("Buffer() = %v, want %v", got, want)
+	}
+}
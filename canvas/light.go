@@ -0,0 +1,77 @@
+package canvas
+
+import (
+	"image/color"
+
+	geom "rasterizer/geometry"
+)
+
+// PointLight is a light that shines equally in all directions from a point.
+type PointLight struct {
+	Pos geom.Vec3
+	// Color is given in sRGB, like the colors of an image.
+	Color     color.RGBA
+	Intensity float32
+	// Radius is the distance at which the light no longer has any effect.
+	Radius float32
+	// Shadow, if set, blocks the light from surfaces that it cannot reach.
+	Shadow *ShadowMap
+}
+
+// illuminate returns the light received from l by a surface at pos with the
+// given normal, as an RGB vector where 1 is full intensity.
+func (l *PointLight) illuminate(pos, normal geom.Vec3) geom.Vec3 {
+	toLight := l.Pos.Sub(pos)
+	distSq := toLight.Dot(toLight)
+	if distSq >= l.Radius*l.Radius {
+		return geom.Vec3{}
+	}
+
+	dist := toLight.Length()
+	lambert := normal.Dot(toLight.Scale(1 / dist))
+	if lambert <= 0 {
+		return geom.Vec3{}
+	}
+
+	// Fall off smoothly so that the light has no visible edge at its radius
+	falloff := 1 - dist/l.Radius
+	amount := l.Intensity * lambert * falloff * falloff
+	if l.Shadow != nil {
+		amount *= 1 - l.Shadow.Strength*(1-l.Shadow.Visibility(pos))
+	}
+	return DecodeSRGB(l.Color).Scale(amount)
+}
+
+// Shade runs the lighting pass: every covered pixel of the GBuffer is lit by
+// the given lights and written to the canvas, whose HDR buffer preserves light
+// brighter than 1 if enabled. Each light is blocked by its own shadow map, so
+// shadowed surfaces keep their ambient light and the light of other lights.
+// Fog set on the canvas is also applied, but its shadow maps are not, as they
+// are for unlit drawing. Ambient is the fraction of the albedo that is visible
+// without any light.
+func (g *GBuffer) Shade(c *Canvas, lights []PointLight, ambient float32) {
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			if !g.Covered(x, y) {
+				continue
+			}
+
+			i := y*g.width + x
+			light := geom.Vec3{X: ambient, Y: ambient, Z: ambient}
+			for j := range lights {
+				light = light.Add(lights[j].illuminate(g.position[i], g.normal[i]))
+			}
+
+			albedo := g.albedo[i]
+			clr := geom.Vec3{
+				X: albedo.X * light.X,
+				Y: albedo.Y * light.Y,
+				Z: albedo.Z * light.Z,
+			}
+			if c.fog != nil {
+				clr = c.fog.apply(clr, g.depth[i], g.position[i])
+			}
+			c.PutPixelHDR(x, y, clr)
+		}
+	}
+}
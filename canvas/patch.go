@@ -0,0 +1,56 @@
+package canvas
+
+import (
+	geom "rasterizer/geometry"
+)
+
+// TessellatePatch returns a grid of triangles approximating the patch, with the
+// given number of segments along each side. Texture coordinates follow the
+// patch's parameters, and the front faces are on the side of the patch's
+// normals.
+func TessellatePatch(patch *geom.BezierPatch, segments int) *IndexedTriangleList {
+	return TessellatePatches([]geom.BezierPatch{*patch}, segments)
+}
+
+// TessellatePatches returns the triangles of all of the patches, as if each one
+// had been tessellated with TessellatePatch, in a single shape.
+func TessellatePatches(patches []geom.BezierPatch, segments int) *IndexedTriangleList {
+	if segments < 1 {
+		segments = 1
+	}
+	side := segments + 1
+	list := &IndexedTriangleList{
+		Vertices:  make([]geom.Vec3, 0, len(patches)*side*side),
+		TexCoords: make([]geom.Vec2, 0, len(patches)*side*side),
+		Normals:   make([]geom.Vec3, 0, len(patches)*side*side),
+		Indices:   make([]int, 0, len(patches)*segments*segments*6),
+	}
+
+	for i := range patches {
+		patch := &patches[i]
+		base := len(list.Vertices)
+		for row := 0; row <= segments; row++ {
+			v := float32(row) / float32(segments)
+			for col := 0; col <= segments; col++ {
+				u := float32(col) / float32(segments)
+				list.Vertices = append(list.Vertices, patch.At(u, v))
+				list.TexCoords = append(list.TexCoords, geom.Vec2{X: u, Y: v})
+				list.Normals = append(list.Normals, patch.Normal(u, v))
+			}
+		}
+
+		for row := 0; row < segments; row++ {
+			for col := 0; col < segments; col++ {
+				i0 := base + row*side + col
+				i1, i2, i3 := i0+1, i0+side, i0+side+1
+				// The normal of a triangle is the cross product of its first two
+				// edges, so going along U and then V matches the patch's normal
+				list.Indices = append(list.Indices,
+					i0, i1, i2,
+					i1, i3, i2,
+				)
+			}
+		}
+	}
+	return list
+}
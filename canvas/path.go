@@ -0,0 +1,149 @@
+package canvas
+
+import (
+	"math"
+
+	geom "rasterizer/geometry"
+)
+
+// Curves are flattened into lines that stray at most this many pixels from
+// the true curve.
+const flattenTolerance = 0.25
+
+// Path is a two-dimensional shape made of lines and curves, in pixel
+// coordinates. It can contain several separate subpaths.
+type Path struct {
+	subpaths [][]geom.Vec2
+	closed   []bool
+}
+
+// MoveTo starts a new subpath at p.
+func (path *Path) MoveTo(p geom.Vec2) {
+	path.subpaths = append(path.subpaths, []geom.Vec2{p})
+	path.closed = append(path.closed, false)
+}
+
+// LineTo adds a line from the current point to p.
+func (path *Path) LineTo(p geom.Vec2) {
+	if len(path.subpaths) == 0 {
+		path.MoveTo(p)
+		return
+	}
+	last := len(path.subpaths) - 1
+	path.subpaths[last] = append(path.subpaths[last], p)
+}
+
+// QuadTo adds a quadratic Bézier curve from the current point to p, using
+// control as its control point.
+func (path *Path) QuadTo(control, p geom.Vec2) {
+	p0 := path.current()
+	n := curveSegments(p0.Distance(control) + control.Distance(p))
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		a := p0.InterpolateTo(control, t)
+		b := control.InterpolateTo(p, t)
+		path.LineTo(a.InterpolateTo(b, t))
+	}
+}
+
+// CubicTo adds a cubic Bézier curve from the current point to p, using c1 and
+// c2 as its control points.
+func (path *Path) CubicTo(c1, c2, p geom.Vec2) {
+	p0 := path.current()
+	n := curveSegments(p0.Distance(c1) + c1.Distance(c2) + c2.Distance(p))
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		a, b, c := p0.InterpolateTo(c1, t), c1.InterpolateTo(c2, t), c2.InterpolateTo(p, t)
+		ab, bc := a.InterpolateTo(b, t), b.InterpolateTo(c, t)
+		path.LineTo(ab.InterpolateTo(bc, t))
+	}
+}
+
+// Arc adds an elliptical arc around center, from angle start to angle end in
+// radians. Angles increase clockwise on the screen, starting from the positive
+// X-axis. A line is added from the current point to the start of the arc, if
+// there is one.
+func (path *Path) Arc(center geom.Vec2, radiusX, radiusY, start, end float32) {
+	sweep := end - start
+	n := ellipseSegments(radiusX, radiusY, sweep)
+	for i := 0; i <= n; i++ {
+		angle := float64(start + sweep*float32(i)/float32(n))
+		path.LineTo(geom.Vec2{
+			X: center.X + radiusX*float32(math.Cos(angle)),
+			Y: center.Y + radiusY*float32(math.Sin(angle)),
+		})
+	}
+}
+
+// Close closes the current subpath with a line back to its start.
+func (path *Path) Close() {
+	if len(path.subpaths) > 0 {
+		path.closed[len(path.closed)-1] = true
+	}
+}
+
+// Rect adds a closed rectangular subpath.
+func (path *Path) Rect(x, y, width, height float32) {
+	path.MoveTo(geom.Vec2{X: x, Y: y})
+	path.LineTo(geom.Vec2{X: x + width, Y: y})
+	path.LineTo(geom.Vec2{X: x + width, Y: y + height})
+	path.LineTo(geom.Vec2{X: x, Y: y + height})
+	path.Close()
+}
+
+// Ellipse adds a closed elliptical subpath.
+func (path *Path) Ellipse(center geom.Vec2, radiusX, radiusY float32) {
+	path.subpaths = append(path.subpaths, nil)
+	path.closed = append(path.closed, false)
+	path.Arc(center, radiusX, radiusY, 0, 2*math.Pi)
+	path.Close()
+}
+
+// Polygon adds a closed subpath through the given points.
+func (path *Path) Polygon(points []geom.Vec2) {
+	if len(points) == 0 {
+		return
+	}
+	path.MoveTo(points[0])
+	for _, p := range points[1:] {
+		path.LineTo(p)
+	}
+	path.Close()
+}
+
+// current returns the last point of the path, or the origin if it is empty.
+func (path *Path) current() geom.Vec2 {
+	if len(path.subpaths) == 0 {
+		return geom.Vec2{}
+	}
+	last := path.subpaths[len(path.subpaths)-1]
+	if len(last) == 0 {
+		return geom.Vec2{}
+	}
+	return last[len(last)-1]
+}
+
+// curveSegments returns how many lines to flatten a curve into, given the length
+// of its control polygon.
+func curveSegments(length float32) int {
+	n := int(math.Ceil(math.Sqrt(float64(length) / flattenTolerance)))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// ellipseSegments returns how many lines to flatten an elliptical arc into, so
+// that no line strays further than flattenTolerance from the true arc.
+func ellipseSegments(radiusX, radiusY, sweep float32) int {
+	radius := math.Max(math.Abs(float64(radiusX)), math.Abs(float64(radiusY)))
+	if radius <= flattenTolerance {
+		return 4
+	}
+	step := 2 * math.Acos(1-flattenTolerance/radius)
+	n := int(math.Ceil(math.Abs(float64(sweep)) / step))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
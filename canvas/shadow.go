@@ -0,0 +1,217 @@
+package canvas
+
+import (
+	"math"
+
+	geom "rasterizer/geometry"
+)
+
+// ShadowMap stores the depth of the scene as seen from a light, so that we can
+// tell whether a point is hidden from the light by something closer to it.
+type ShadowMap struct {
+	depth      *Canvas
+	resolution int
+	// Transforms scene positions into the light's space, where the light looks
+	// along the Z-axis.
+	rotation geom.Mat3
+	origin   geom.Vec3
+	// Spot lights use a perspective projection, and directional lights an
+	// orthographic one.
+	perspective bool
+	// Scales light-space X and Y so that the visible area spans -1 to 1.
+	scale float32
+
+	// Bias is subtracted from a point's depth before comparing it to the map, to
+	// stop surfaces from shadowing themselves.
+	Bias float32
+	// PCFRadius is the number of texels sampled either side of a point to soften
+	// the edges of shadows. Zero gives hard shadows.
+	PCFRadius int
+	// Strength is how much light a shadow blocks, between 0 and 1.
+	Strength float32
+}
+
+// NewDirectionalShadowMap returns a ShadowMap for a light shining in direction
+// dir, covering a square area of the given half-width around center.
+func NewDirectionalShadowMap(dir, center geom.Vec3, halfWidth float32, resolution int) *ShadowMap {
+	// Place the origin well behind the area so that everything in it has
+	// positive depth.
+	origin := center.Sub(dir.Normalize().Scale(4 * halfWidth))
+	return newShadowMap(dir, origin, false, 1/halfWidth, resolution)
+}
+
+// NewSpotShadowMap returns a ShadowMap for a spot light at pos shining in
+// direction dir, whose cone has the given field of view in radians.
+func NewSpotShadowMap(pos, dir geom.Vec3, fov float32, resolution int) *ShadowMap {
+	scale := 1 / float32(math.Tan(float64(fov)/2))
+	return newShadowMap(dir, pos, true, scale, resolution)
+}
+
+func newShadowMap(dir, origin geom.Vec3, perspective bool, scale float32, resolution int) *ShadowMap {
+	return &ShadowMap{
+		depth:       NewCanvas(resolution, resolution),
+		resolution:  resolution,
+		rotation:    *geom.LookRotation(dir, geom.Vec3{X: 0, Y: 1, Z: 0}),
+		origin:      origin,
+		perspective: perspective,
+		scale:       scale,
+		Bias:        0.05,
+		Strength:    0.6,
+	}
+}
+
+// Clear resets the depth of the map, ready to draw the scene again.
+func (s *ShadowMap) Clear() {
+	s.depth.Clear()
+}
+
+// shadowNearPlane is the closest depth from the light that is drawn into a
+// shadow map. Triangles that cross it are clipped.
+const shadowNearPlane = 0.1
+
+// DrawTriangle records the depth of the triangle with the given scene positions.
+// Parts of the triangle behind the light or outside the map are clipped off.
+func (s *ShadowMap) DrawTriangle(v0, v1, v2 geom.Vec3) {
+	polygon := s.clip([]geom.Vec3{s.toLight(v0), s.toLight(v1), s.toLight(v2)})
+	for i := 1; i+1 < len(polygon); i++ {
+		p0, p1, p2 := s.toMap(polygon[0]), s.toMap(polygon[i]), s.toMap(polygon[i+1])
+
+		// With perspective, the rasterizer expects the Z-component to hold 1/Z
+		// so that depth is interpolated correctly
+		if s.perspective {
+			p0.Z, p1.Z, p2.Z = 1/p0.Z, 1/p1.Z, 1/p2.Z
+		}
+		rasterizeTriangle(
+			TexVertex{Pos: p0},
+			TexVertex{Pos: p1},
+			TexVertex{Pos: p2},
+			s.perspective,
+			func(x, y int, v TexVertex) {
+				s.depth.TestAndSet(x, y, v.Pos.Z)
+			},
+		)
+	}
+}
+
+// clip clips a convex polygon in the light's space against the near plane and
+// the edges of the map. The result has no vertices if none of the polygon is
+// on the map.
+func (s *ShadowMap) clip(polygon []geom.Vec3) []geom.Vec3 {
+	// The edges of the map are at 1 or -1 after scaling, and spread out with
+	// depth if the light has perspective
+	extent := func(v geom.Vec3) float32 {
+		if s.perspective {
+			return v.Z
+		}
+		return 1
+	}
+	planes := []func(v geom.Vec3) float32{
+		func(v geom.Vec3) float32 { return v.Z - shadowNearPlane },
+		func(v geom.Vec3) float32 { return extent(v) - v.X*s.scale },
+		func(v geom.Vec3) float32 { return extent(v) + v.X*s.scale },
+		func(v geom.Vec3) float32 { return extent(v) - v.Y*s.scale },
+		func(v geom.Vec3) float32 { return extent(v) + v.Y*s.scale },
+	}
+	for _, distance := range planes {
+		polygon = clipPolygonToPlane(polygon, distance)
+	}
+	return polygon
+}
+
+// clipPolygonToPlane keeps the part of a convex polygon where the distance,
+// which must change linearly across the polygon, is not negative. The order of
+// its vertices is preserved.
+func clipPolygonToPlane(polygon []geom.Vec3, distance func(v geom.Vec3) float32) []geom.Vec3 {
+	clipped := make([]geom.Vec3, 0, len(polygon)+1)
+	for i, curr := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		currDist, nextDist := distance(curr), distance(next)
+		if currDist >= 0 {
+			clipped = append(clipped, curr)
+		}
+		if (currDist >= 0) != (nextDist >= 0) {
+			alpha := currDist / (currDist - nextDist)
+			clipped = append(clipped, curr.Add(next.Sub(curr).Scale(alpha)))
+		}
+	}
+	return clipped
+}
+
+// project returns the position on the map in pixels, and the depth from the
+// light, of a point in the scene. It returns false if the point is behind the
+// light.
+func (s *ShadowMap) project(pos geom.Vec3) (geom.Vec3, bool) {
+	local := s.toLight(pos)
+	if local.Z <= 0 {
+		return geom.Vec3{}, false
+	}
+	return s.toMap(local), true
+}
+
+// toLight transforms a point in the scene into the light's space.
+func (s *ShadowMap) toLight(pos geom.Vec3) geom.Vec3 {
+	return s.rotation.VecMul(pos.Sub(s.origin))
+}
+
+// toMap returns the position on the map in pixels, and the depth, of a point in
+// the light's space in front of the light.
+func (s *ShadowMap) toMap(local geom.Vec3) geom.Vec3 {
+	if s.perspective {
+		local.X /= local.Z
+		local.Y /= local.Z
+	}
+
+	half := float32(s.resolution) / 2
+	return geom.Vec3{
+		X: (1 + local.X*s.scale) * half,
+		Y: (1 - local.Y*s.scale) * half,
+		Z: local.Z,
+	}
+}
+
+// Visibility returns the fraction of light that reaches the point in the scene,
+// where 0 is fully in shadow. Points outside the map are always lit.
+func (s *ShadowMap) Visibility(pos geom.Vec3) float32 {
+	p, ok := s.project(pos)
+	if !ok {
+		return 1
+	}
+
+	px, py := int(math.Floor(float64(p.X))), int(math.Floor(float64(p.Y)))
+	depth := p.Z - s.Bias
+
+	samples, lit := 0, 0
+	for dy := -s.PCFRadius; dy <= s.PCFRadius; dy++ {
+		for dx := -s.PCFRadius; dx <= s.PCFRadius; dx++ {
+			x, y := px+dx, py+dy
+			if x < 0 || y < 0 || x >= s.resolution || y >= s.resolution {
+				continue
+			}
+			samples++
+			if depth <= s.depth.DepthAt(x, y) {
+				lit++
+			}
+		}
+	}
+
+	if samples == 0 {
+		return 1
+	}
+	return float32(lit) / float32(samples)
+}
+
+// SetShadowMaps darkens everything subsequently drawn on the Canvas that is in
+// shadow in any of the given maps. Lit surfaces drawn with GBuffer.Shade use
+// the shadow map of each PointLight instead.
+func (c *Canvas) SetShadowMaps(maps ...*ShadowMap) {
+	c.shadowMaps = maps
+}
+
+// shadowFactor returns how much a surface at pos is darkened by shadows.
+func (c *Canvas) shadowFactor(pos geom.Vec3) float32 {
+	factor := float32(1)
+	for _, s := range c.shadowMaps {
+		factor *= 1 - s.Strength*(1-s.Visibility(pos))
+	}
+	return factor
+}
@@ -0,0 +1,31 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	geom "rasterizer/geometry"
+)
+
+func TestShadowMapClipping(t *testing.T) {
+	// A spot light looking along the Z-axis, above a ground plane that reaches
+	// behind it
+	s := NewSpotShadowMap(geom.Vec3{}, geom.Vec3{Z: 1}, math.Pi/2, 64)
+	s.Bias = 0.01
+	s.DrawTriangle(geom.Vec3{X: -50, Y: -1, Z: -50}, geom.Vec3{X: 50, Y: -1, Z: -50}, geom.Vec3{Y: -1, Z: 50})
+
+	tests := []struct {
+		name string
+		pos  geom.Vec3
+		want float32
+	}{
+		{"above the ground", geom.Vec3{Y: -0.5, Z: 3}, 1},
+		{"below the ground", geom.Vec3{Y: -2, Z: 3}, 0},
+		{"behind the light", geom.Vec3{Y: -2, Z: -3}, 1},
+	}
+	for _, tt := range tests {
+		if got := s.Visibility(tt.pos); math.Abs(float64(got-tt.want)) > 1e-6 {
+			t.Errorf("%s: Visibility() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
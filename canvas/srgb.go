@@ -0,0 +1,90 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+
+	geom "rasterizer/geometry"
+)
+
+// Number of entries used to look up the sRGB encoding of a linear value.
+const linearSteps = 4096
+
+var (
+	srgbToLinearTable [256]float32
+	linearToSRGBTable [linearSteps]uint8
+)
+
+func init() {
+	for i := range srgbToLinearTable {
+		srgbToLinearTable[i] = srgbToLinear(float64(i) / 0xFF)
+	}
+	for i := range linearToSRGBTable {
+		linearToSRGBTable[i] = uint8(linearToSRGB(float64(i)/(linearSteps-1))*0xFF + 0.5)
+	}
+}
+
+// srgbToLinear applies the sRGB transfer function in reverse to x.
+func srgbToLinear(x float64) float32 {
+	if x <= 0.04045 {
+		return float32(x / 12.92)
+	}
+	return float32(math.Pow((x+0.055)/1.055, 2.4))
+}
+
+// linearToSRGB applies the sRGB transfer function to x.
+func linearToSRGB(x float64) float64 {
+	if x <= 0.0031308 {
+		return x * 12.92
+	}
+	return 1.055*math.Pow(x, 1/2.4) - 0.055
+}
+
+// DecodeSRGB converts an sRGB color to a linear RGB vector with components
+// between 0 and 1, as used for shading. Any alpha is ignored.
+func DecodeSRGB(clr color.Color) geom.Vec3 {
+	rgba := color.NRGBAModel.Convert(clr).(color.NRGBA)
+	return geom.Vec3{
+		X: srgbToLinearTable[rgba.R],
+		Y: srgbToLinearTable[rgba.G],
+		Z: srgbToLinearTable[rgba.B],
+	}
+}
+
+// EncodeSRGB converts a linear RGB vector to an opaque sRGB color, saturating
+// components that are out of range.
+func EncodeSRGB(v geom.Vec3) color.RGBA {
+	return color.RGBA{
+		R: encodeComponent(v.X),
+		G: encodeComponent(v.Y),
+		B: encodeComponent(v.Z),
+		A: 0xFF,
+	}
+}
+
+func encodeComponent(x float32) uint8 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 0xFF
+	default:
+		return linearToSRGBTable[int(x*(linearSteps-1)+0.5)]
+	}
+}
+
+// clampColor clamps each component of the color to between 0 and 1.
+func clampColor(v geom.Vec3) geom.Vec3 {
+	return geom.Vec3{X: clamp01(v.X), Y: clamp01(v.Y), Z: clamp01(v.Z)}
+}
+
+func clamp01(x float32) float32 {
+	switch {
+	case x < 0:
+		return 0
+	case x > 1:
+		return 1
+	default:
+		return x
+	}
+}
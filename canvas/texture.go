@@ -8,9 +8,10 @@ import (
 	geom "rasterizer/geometry"
 )
 
-// Texture is a map that can be used to shade a surface.
+// Texture is a map that can be used to shade a surface. Shading is done in
+// linear RGB, so that colors can be blended and lit correctly.
 type Texture interface {
-	shade(v TexVertex) color.Color
+	shade(v TexVertex) geom.Vec3
 }
 
 // ImageTextureClamped uses an Image as the texture map. Vertices that go
@@ -18,9 +19,12 @@ type Texture interface {
 type ImageTextureClamped struct {
 	Img   image.Image
 	Scale float32
+	// Linear should be set if the image holds data rather than colors, eg. a
+	// normal map, so that it is not decoded from sRGB.
+	Linear bool
 }
 
-func (tex *ImageTextureClamped) shade(v TexVertex) color.Color {
+func (tex *ImageTextureClamped) shade(v TexVertex) geom.Vec3 {
 	max := tex.Img.Bounds().Max
 
 	scaledX := int(v.TexPos.X * float32(max.X) / tex.Scale)
@@ -33,7 +37,7 @@ func (tex *ImageTextureClamped) shade(v TexVertex) color.Color {
 		scaledY = max.Y - 1
 	}
 
-	return tex.Img.At(scaledX, scaledY)
+	return decodeImageColor(tex.Img.At(scaledX, scaledY), tex.Linear)
 }
 
 // ImageTextureWrapped uses an Image as the texture map. Vertices that go
@@ -41,14 +45,86 @@ func (tex *ImageTextureClamped) shade(v TexVertex) color.Color {
 type ImageTextureWrapped struct {
 	Img   image.Image
 	Scale float32
+	// Linear should be set if the image holds data rather than colors, eg. a
+	// normal map, so that it is not decoded from sRGB.
+	Linear bool
 }
 
-func (tex *ImageTextureWrapped) shade(v TexVertex) color.Color {
-	max := tex.Img.Bounds().Max
-	// TODO: Fix the Mod issue. Should be mod by max.X instead of max.X-1
-	scaledX := math.Mod(float64(v.TexPos.X*float32(max.X)/tex.Scale), float64(max.X-1))
-	scaledY := math.Mod(float64(v.TexPos.Y*float32(max.Y)/tex.Scale), float64(max.Y-1))
-	return tex.Img.At(int(scaledX), int(scaledY))
+func (tex *ImageTextureWrapped) shade(v TexVertex) geom.Vec3 {
+	bounds := tex.Img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	// Negative coordinates wrap around too, so they are rounded down rather
+	// than towards zero
+	x := wrapInt(int(math.Floor(float64(v.TexPos.X*float32(w)/tex.Scale))), w)
+	y := wrapInt(int(math.Floor(float64(v.TexPos.Y*float32(h)/tex.Scale))), h)
+	return decodeImageColor(tex.Img.At(bounds.Min.X+x, bounds.Min.Y+y), tex.Linear)
+}
+
+// SolidColor shades every point of a surface with the same color.
+type SolidColor struct {
+	Color color.Color
+}
+
+func (tex *SolidColor) shade(v TexVertex) geom.Vec3 {
+	return DecodeSRGB(tex.Color)
+}
+
+// VertexColor shades surfaces with the colors of their vertices, blended
+// smoothly between them.
+type VertexColor struct{}
+
+func (tex *VertexColor) shade(v TexVertex) geom.Vec3 {
+	return v.Color
+}
+
+// Tinted multiplies the colors of a texture by a linear RGB factor. Without a
+// texture, surfaces are shaded with the factor itself.
+type Tinted struct {
+	Texture Texture
+	Factor  geom.Vec3
+}
+
+func (tex *Tinted) shade(v TexVertex) geom.Vec3 {
+	if tex.Texture == nil {
+		return tex.Factor
+	}
+	return tex.Texture.shade(v).Mul(tex.Factor)
+}
+
+// decodeImageColor converts a color sampled from an image to linear RGB.
+func decodeImageColor(clr color.Color, linear bool) geom.Vec3 {
+	if linear {
+		rgba := color.RGBAModel.Convert(clr).(color.RGBA)
+		return geom.Vec3{
+			X: float32(rgba.R) / 0xFF,
+			Y: float32(rgba.G) / 0xFF,
+			Z: float32(rgba.B) / 0xFF,
+		}
+	}
+	return DecodeSRGB(clr)
+}
+
+// shade allows a Canvas to be used as a Texture, so that the result of an
+// off-screen render can be drawn onto another surface. Texture coordinates wrap
+// around the edge of the canvas. A canvas should not be sampled while it is
+// being drawn to.
+func (c *Canvas) shade(v TexVertex) geom.Vec3 {
+	w, h := c.Dimensions()
+	x := wrapInt(int(math.Floor(float64(v.TexPos.X*float32(w)))), w)
+	y := wrapInt(int(math.Floor(float64(v.TexPos.Y*float32(h)))), h)
+	if c.hdr != nil {
+		return clampColor(c.toneMap(c.hdr[y*w+x].Scale(c.exposure)))
+	}
+	return DecodeSRGB(c.image.RGBAAt(x, y))
+}
+
+// wrapInt returns x modulo n, in the range [0, n).
+func wrapInt(x, n int) int {
+	x %= n
+	if x < 0 {
+		x += n
+	}
+	return x
 }
 
 // TexVertex contains a vertex's position both on a two-dimensional surface (eg. a Canvas),
@@ -60,36 +136,54 @@ type TexVertex struct {
 	Pos geom.Vec3
 	// Position of the vertex on the texture map, where 0 <= X, Y < 1.
 	TexPos geom.Vec2
+	// Position of the vertex in the scene, before perspective is applied.
+	WorldPos geom.Vec3
+	// Surface normal at the vertex, in the same space as WorldPos.
+	Normal geom.Vec3
+	// Linear RGB color of the vertex, used by VertexColor.
+	Color geom.Vec3
 }
 
 // Scale returns the scalar-vector product kv.
 func (v TexVertex) Scale(k float32) TexVertex {
 	return TexVertex{
-		Pos:    v.Pos.Scale(k),
-		TexPos: v.TexPos.Scale(k),
+		Pos:      v.Pos.Scale(k),
+		TexPos:   v.TexPos.Scale(k),
+		WorldPos: v.WorldPos.Scale(k),
+		Normal:   v.Normal.Scale(k),
+		Color:    v.Color.Scale(k),
 	}
 }
 
 // Sub returns vector v - u.
 func (v TexVertex) Sub(u TexVertex) TexVertex {
 	return TexVertex{
-		Pos:    v.Pos.Sub(u.Pos),
-		TexPos: v.TexPos.Sub(u.TexPos),
+		Pos:      v.Pos.Sub(u.Pos),
+		TexPos:   v.TexPos.Sub(u.TexPos),
+		WorldPos: v.WorldPos.Sub(u.WorldPos),
+		Normal:   v.Normal.Sub(u.Normal),
+		Color:    v.Color.Sub(u.Color),
 	}
 }
 
 // Add returns vector v + u.
 func (v TexVertex) Add(u TexVertex) TexVertex {
 	return TexVertex{
-		Pos:    v.Pos.Add(u.Pos),
-		TexPos: v.TexPos.Add(u.TexPos),
+		Pos:      v.Pos.Add(u.Pos),
+		TexPos:   v.TexPos.Add(u.TexPos),
+		WorldPos: v.WorldPos.Add(u.WorldPos),
+		Normal:   v.Normal.Add(u.Normal),
+		Color:    v.Color.Add(u.Color),
 	}
 }
 
 // InterpolateTo interpolates the vector towards another vector u by step alpha.
 func (v TexVertex) InterpolateTo(u TexVertex, alpha float32) TexVertex {
 	return TexVertex{
-		Pos:    v.Pos.InterpolateTo(u.Pos, alpha),
-		TexPos: v.TexPos.InterpolateTo(u.TexPos, alpha),
+		Pos:      v.Pos.InterpolateTo(u.Pos, alpha),
+		TexPos:   v.TexPos.InterpolateTo(u.TexPos, alpha),
+		WorldPos: v.WorldPos.InterpolateTo(u.WorldPos, alpha),
+		Normal:   v.Normal.InterpolateTo(u.Normal, alpha),
+		Color:    v.Color.InterpolateTo(u.Color, alpha),
 	}
 }
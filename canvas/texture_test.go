@@ -0,0 +1,35 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	geom "rasterizer/geometry"
+)
+
+func TestImageTextureWrapped(t *testing.T) {
+	// A row of four pixels, whose bounds do not start at the origin
+	img := image.NewGray(image.Rect(2, 5, 6, 6))
+	for x := 0; x < 4; x++ {
+		img.SetGray(2+x, 5, color.Gray{Y: uint8(x)})
+	}
+	tex := &ImageTextureWrapped{Img: img, Scale: 1, Linear: true}
+	tests := []struct {
+		u    float32
+		want int
+	}{
+		{0.1, 0},
+		{0.9, 3},
+		{1.1, 0},
+		{-0.1, 3},
+		{-0.9, 0},
+		{2.6, 2},
+	}
+	for _, tt := range tests {
+		got := tex.shade(TexVertex{TexPos: geom.Vec2{X: tt.u, Y: 0.5}})
+		if want := float32(tt.want) / 0xFF; got.X != want {
+			t.Errorf("u = %v: sampled %v, want pixel %d", tt.u, got.X*0xFF, tt.want)
+		}
+	}
+}
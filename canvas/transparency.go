@@ -0,0 +1,130 @@
+package canvas
+
+import (
+	"image"
+
+	geom "rasterizer/geometry"
+)
+
+// TransparencyMode determines how transparent surfaces are combined.
+type TransparencyMode int
+
+const (
+	// SortedBlending blends each transparent surface with the canvas as it is
+	// drawn, which is only correct if surfaces are drawn from back to front.
+	SortedBlending TransparencyMode = iota
+	// WeightedBlended accumulates transparent surfaces in any order, weighting
+	// them by depth, and combines them in ResolveTransparency. It approximates
+	// the correct result even when transparent surfaces intersect.
+	WeightedBlended
+)
+
+// oitBuffers accumulate transparent surfaces for weighted blended
+// order-independent transparency.
+type oitBuffers struct {
+	// Sum of the weighted, premultiplied colors of each surface.
+	accum []geom.Vec3
+	// Sum of the weighted opacities of each surface.
+	weight []float32
+	// Product of the transparency of each surface; how much of the background
+	// is still visible.
+	revealage []float32
+}
+
+// SetOpacity sets the opacity of everything subsequently drawn on the Canvas.
+// Surfaces with opacity below 1 are tested against the depth buffer but do not
+// write to it, so that surfaces behind them remain visible.
+func (c *Canvas) SetOpacity(opacity float32) {
+	c.opacity = clamp01(opacity)
+}
+
+// SetTransparencyMode sets how transparent surfaces are combined.
+func (c *Canvas) SetTransparencyMode(mode TransparencyMode) {
+	c.transparencyMode = mode
+}
+
+// transparentShader returns a pixelFunc that combines transparent surfaces with
+// the canvas using the current TransparencyMode.
+func (c *Canvas) transparentShader(tex Texture) pixelFunc {
+	return func(x, y int, v TexVertex) {
+		if point := (image.Point{X: x, Y: y}); !point.In(c.scissor) {
+			return
+		}
+		if v.Pos.Z >= c.depthBuffer[c.index(x, y)] {
+			return
+		}
+
+		clr := c.shadePixel(tex, v)
+		if c.transparencyMode == WeightedBlended {
+			c.accumulate(x, y, clr, v.Pos.Z)
+			return
+		}
+
+		// Blend in linear space, so that the result has the correct brightness
+		dst := c.ColorAt(x, y)
+		c.PutPixelHDR(x, y, dst.InterpolateTo(clr, c.opacity))
+	}
+}
+
+// accumulate adds a transparent surface to the order-independent transparency
+// buffers, using the depth weighting from McGuire and Bavoil (2013).
+func (c *Canvas) accumulate(x, y int, clr geom.Vec3, depth float32) {
+	w, h := c.Dimensions()
+	if c.oit == nil {
+		c.oit = &oitBuffers{
+			accum:     make([]geom.Vec3, w*h),
+			weight:    make([]float32, w*h),
+			revealage: make([]float32, w*h),
+		}
+		for i := range c.oit.revealage {
+			c.oit.revealage[i] = 1
+		}
+	}
+
+	// Closer surfaces get a larger weight, so they dominate the result
+	d := depth / 5
+	weight := clampRange(10/(1e-5+d*d+pow6(depth/200)), 1e-2, 3e3)
+
+	i := y*w + x
+	c.oit.accum[i] = c.oit.accum[i].Add(clr.Scale(c.opacity * weight))
+	c.oit.weight[i] += c.opacity * weight
+	c.oit.revealage[i] *= 1 - c.opacity
+}
+
+// ResolveTransparency combines the transparent surfaces accumulated in
+// WeightedBlended mode with the canvas, and resets the accumulation.
+func (c *Canvas) ResolveTransparency() {
+	if c.oit == nil {
+		return
+	}
+
+	w, h := c.Dimensions()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if c.oit.weight[i] == 0 {
+				continue
+			}
+			average := c.oit.accum[i].Scale(1 / c.oit.weight[i])
+			dst := c.ColorAt(x, y)
+			c.PutPixelHDR(x, y, average.InterpolateTo(dst, c.oit.revealage[i]))
+		}
+	}
+	c.oit = nil
+}
+
+func clampRange(x, min, max float32) float32 {
+	switch {
+	case x < min:
+		return min
+	case x > max:
+		return max
+	default:
+		return x
+	}
+}
+
+func pow6(x float32) float32 {
+	x3 := x * x * x
+	return x3 * x3
+}
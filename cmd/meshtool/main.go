@@ -0,0 +1,195 @@
+// Command meshtool cleans up a mesh and computes its normals, converting
+// between the OBJ, STL and PLY formats.
+//
+// Usage:
+//
+//	meshtool [flags] input output
+//
+// The formats are chosen by the files' extensions. OBJ files can only be read,
+// and all of their meshes are merged into one. The steps that are enabled are
+// run in the order of the flags below.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+	"rasterizer/mesh"
+	"rasterizer/obj"
+	"rasterizer/ply"
+	"rasterizer/stl"
+)
+
+var (
+	weld       = flag.Float64("weld", -1, "merge vertices within this distance of each other")
+	degenerate = flag.Bool("degenerate", false, "remove triangles with no area")
+	duplicates = flag.Bool("duplicates", false, "remove triangles repeated with the same vertices")
+	unused     = flag.Bool("unused", false, "remove vertices that no triangle uses")
+	flip       = flag.Bool("flip", false, "turn every triangle to face the other way")
+	normals    = flag.String("normals", "", "compute `flat` or `smooth` normals")
+	crease     = flag.Float64("crease", 60, "the angle in degrees above which smooth normals are not blended")
+	ascii      = flag.Bool("ascii", false, "write STL and PLY files as text")
+	bigEndian  = flag.Bool("big-endian", false, "write binary PLY files as big-endian")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("meshtool: ")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: meshtool [flags] input output\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	input, output := flag.Arg(0), flag.Arg(1)
+
+	list, err := load(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("read %d vertices and %d triangles", len(list.Vertices), len(list.Triangles()))
+
+	if *weld >= 0 {
+		log.Printf("welded %d vertices", mesh.Weld(list, float32(*weld)))
+	}
+	if *degenerate {
+		log.Printf("removed %d degenerate triangles", mesh.RemoveDegenerateTriangles(list))
+	}
+	if *duplicates {
+		log.Printf("removed %d duplicate triangles", mesh.RemoveDuplicateTriangles(list))
+	}
+	if *unused {
+		log.Printf("removed %d unused vertices", mesh.RemoveUnusedVertices(list))
+	}
+	if *flip {
+		mesh.FlipWinding(list)
+	}
+	switch *normals {
+	case "":
+	case "flat":
+		mesh.FlatNormals(list)
+	case "smooth":
+		mesh.SmoothNormals(list, float32(*crease*math.Pi/180))
+	default:
+		log.Fatalf("unknown kind of normals %q", *normals)
+	}
+
+	if err := save(output, list); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d vertices and %d triangles", len(list.Vertices), len(list.Triangles()))
+}
+
+// load reads a mesh from a file in the format given by its extension.
+func load(path string) (*canvas.IndexedTriangleList, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obj":
+		model, err := obj.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		lists := make([]*canvas.IndexedTriangleList, len(model.Meshes))
+		for i, m := range model.Meshes {
+			lists[i] = m.Triangles
+		}
+		return merge(lists), nil
+	case ".stl":
+		return stl.Load(path)
+	case ".ply":
+		return ply.Load(path)
+	default:
+		return nil, fmt.Errorf("%s: unknown format", path)
+	}
+}
+
+// save writes a mesh to a file in the format given by its extension.
+func save(path string, list *canvas.IndexedTriangleList) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".stl" && ext != ".ply" {
+		return fmt.Errorf("%s: cannot write this format", path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ext == ".stl" && *ascii:
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		err = stl.WriteASCII(f, list, name)
+	case ext == ".stl":
+		err = stl.Write(f, list)
+	case *ascii:
+		err = ply.Write(f, list, ply.ASCII)
+	case *bigEndian:
+		err = ply.Write(f, list, ply.BinaryBigEndian)
+	default:
+		err = ply.Write(f, list, ply.BinaryLittleEndian)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// merge returns the triangles of all of the shapes as a single triangle list.
+// If only some of the shapes have normals, the others are given flat normals,
+// changing them in place, since zero normals would shade them black. Other
+// attributes that only some of the shapes have are zero for the others.
+func merge(lists []*canvas.IndexedTriangleList) *canvas.IndexedTriangleList {
+	merged := &canvas.IndexedTriangleList{Topology: canvas.TriangleList}
+	var hasTexCoords, hasNormals, hasColors bool
+	for _, l := range lists {
+		hasTexCoords = hasTexCoords || l.TexCoords != nil
+		hasNormals = hasNormals || l.Normals != nil
+		hasColors = hasColors || l.Colors != nil
+	}
+	if hasNormals {
+		for _, l := range lists {
+			if l.Normals == nil {
+				mesh.FlatNormals(l)
+			}
+		}
+	}
+
+	for _, l := range lists {
+		base := len(merged.Vertices)
+		merged.Vertices = append(merged.Vertices, l.Vertices...)
+		if hasTexCoords {
+			if l.TexCoords != nil {
+				merged.TexCoords = append(merged.TexCoords, l.TexCoords...)
+			} else {
+				merged.TexCoords = append(merged.TexCoords, make([]geom.Vec2, len(l.Vertices))...)
+			}
+		}
+		merged.Normals = append(merged.Normals, l.Normals...)
+		if hasColors {
+			merged.Colors = appendOrZero(merged.Colors, l.Colors, len(l.Vertices))
+		}
+		for _, tri := range l.Triangles() {
+			merged.Indices = append(merged.Indices, base+tri[0], base+tri[1], base+tri[2])
+		}
+	}
+	return merged
+}
+
+// appendOrZero appends values to dst, or n zero vectors if there are none.
+func appendOrZero(dst, values []geom.Vec3, n int) []geom.Vec3 {
+	if values == nil {
+		return append(dst, make([]geom.Vec3, n)...)
+	}
+	return append(dst, values...)
+}
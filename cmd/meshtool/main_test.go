@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	geom "rasterizer/geometry"
+	"rasterizer/mesh"
+)
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	// Two objects that share no vertices, with the second only having normals
+	objFile := filepath.Join(dir, "shapes.obj")
+	err := ioutil.WriteFile(objFile, []byte(`o a
+v 0 0 0
+v 1 0 0
+v 0 1 0
+f 1 2 3
+o b
+v 0 0 1
+v 1 0 1
+v 0 1 1
+v 1 1 1
+vn 0 0 1
+f 4//1 5//1 6//1 7//1
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := load(objFile)
+	if err != nil {
+		t.Fatalf("load(%s): %v", objFile, err)
+	}
+	if len(list.Vertices) != 7 || len(list.Triangles()) != 3 {
+		t.Fatalf("merged %d vertices and %d triangles, want 7 and 3", len(list.Vertices), len(list.Triangles()))
+	}
+	// The first object is given flat normals, which face the same way as the
+	// second object's
+	if len(list.Normals) != len(list.Vertices) {
+		t.Errorf("merged %d normals, want %d", len(list.Normals), len(list.Vertices))
+	}
+	for i, n := range list.Normals {
+		if want := (geom.Vec3{Z: -1}); !n.ApproxEqual(want, 1e-6) {
+			t.Errorf("normal %d = %v, want %v", i, n, want)
+		}
+	}
+
+	mesh.FlatNormals(list)
+	for _, name := range []string{"shapes.stl", "shapes.ply"} {
+		path := filepath.Join(dir, name)
+		if err := save(path, list); err != nil {
+			t.Fatalf("save(%s): %v", path, err)
+		}
+		got, err := load(path)
+		if err != nil {
+			t.Fatalf("load(%s): %v", path, err)
+		}
+		if len(got.Triangles()) != 3 {
+			t.Errorf("%s: read %d triangles, want 3", name, len(got.Triangles()))
+		}
+	}
+
+	if err := save(filepath.Join(dir, "shapes.obj"), list); err == nil {
+		t.Errorf("save() wrote an OBJ file")
+	}
+	if _, err := load(filepath.Join(dir, "shapes.txt")); err == nil {
+		t.Errorf("load() read a file of unknown format")
+	}
+}
@@ -0,0 +1,107 @@
+package geometry
+
+import "math"
+
+// AABB is an axis-aligned bounding box.
+type AABB struct {
+	Min, Max Vec3
+}
+
+// Sphere is a bounding sphere.
+type Sphere struct {
+	Center Vec3
+	Radius float32
+}
+
+// AABBFromPoints returns the smallest box containing all of the points. With no
+// points, the box is empty.
+func AABBFromPoints(points []Vec3) AABB {
+	inf := float32(math.Inf(1))
+	box := AABB{
+		Min: Vec3{X: inf, Y: inf, Z: inf},
+		Max: Vec3{X: -inf, Y: -inf, Z: -inf},
+	}
+	for _, p := range points {
+		box.Min = box.Min.Min(p)
+		box.Max = box.Max.Max(p)
+	}
+	return box
+}
+
+// Empty returns whether the box contains no points.
+func (b AABB) Empty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+// Center returns the point in the middle of the box.
+func (b AABB) Center() Vec3 {
+	return b.Min.Add(b.Max).Scale(0.5)
+}
+
+// Extents returns half the size of the box along each axis.
+func (b AABB) Extents() Vec3 {
+	return b.Max.Sub(b.Min).Scale(0.5)
+}
+
+// Contains returns whether p is inside the box.
+func (b AABB) Contains(p Vec3) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X &&
+		p.Y >= b.Min.Y && p.Y <= b.Max.Y &&
+		p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+// Union returns the smallest box containing both boxes.
+func (b AABB) Union(c AABB) AABB {
+	return AABB{Min: b.Min.Min(c.Min), Max: b.Max.Max(c.Max)}
+}
+
+// SphereFromPoints returns a sphere containing all of the points. It is centred
+// on their bounding box, so it is not always the smallest such sphere, but it is
+// close and cheap to compute.
+func SphereFromPoints(points []Vec3) Sphere {
+	if len(points) == 0 {
+		return Sphere{}
+	}
+	center := AABBFromPoints(points).Center()
+	var radiusSquared float32
+	for _, p := range points {
+		radiusSquared = max32(radiusSquared, p.Sub(center).LengthSquared())
+	}
+	return Sphere{Center: center, Radius: sqrt(radiusSquared)}
+}
+
+// Contains returns whether p is inside the sphere.
+func (s Sphere) Contains(p Vec3) bool {
+	return p.Sub(s.Center).LengthSquared() <= s.Radius*s.Radius
+}
+
+// Transform returns the smallest axis-aligned box containing the box after it
+// has been transformed by m.
+func (b AABB) Transform(m *Mat4) AABB {
+	if b.Empty() {
+		return b
+	}
+	center := m.TransformPoint(b.Center())
+	extents := b.Extents()
+
+	// Each axis of the new box is as long as the sum of the lengths of the
+	// transformed edges of the old box along it
+	linear := m.Mat3()
+	var newExtents Vec3
+	newExtents.X = abs32(linear[0][0])*extents.X + abs32(linear[0][1])*extents.Y + abs32(linear[0][2])*extents.Z
+	newExtents.Y = abs32(linear[1][0])*extents.X + abs32(linear[1][1])*extents.Y + abs32(linear[1][2])*extents.Z
+	newExtents.Z = abs32(linear[2][0])*extents.X + abs32(linear[2][1])*extents.Y + abs32(linear[2][2])*extents.Z
+	return AABB{Min: center.Sub(newExtents), Max: center.Add(newExtents)}
+}
+
+// Transform returns a sphere containing the sphere after it has been
+// transformed by m. If m scales unevenly, the sphere is scaled by the largest
+// factor.
+func (s Sphere) Transform(m *Mat4) Sphere {
+	var scale float32
+	for j := 0; j < 3; j++ {
+		axis := Vec3{X: m[0][j], Y: m[1][j], Z: m[2][j]}
+		scale = max32(scale, axis.Length())
+	}
+	return Sphere{Center: m.TransformPoint(s.Center), Radius: s.Radius * scale}
+}
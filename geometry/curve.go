@@ -0,0 +1,259 @@
+package geometry
+
+import "fmt"
+
+// Curves are subdivided at most this many times when flattened, which limits
+// the number of points to 2^maxSubdivisions per span.
+const maxSubdivisions = 16
+
+// CubicBezier is a cubic Bézier curve, which starts at P0 heading towards P1,
+// and ends at P3 arriving from the direction of P2.
+type CubicBezier struct {
+	P0, P1, P2, P3 Vec3
+}
+
+// At returns the point on the curve at parameter t, between 0 and 1.
+func (b CubicBezier) At(t float32) Vec3 {
+	// De Casteljau's algorithm, which repeatedly interpolates the control points
+	a, c, d := b.P0.InterpolateTo(b.P1, t), b.P1.InterpolateTo(b.P2, t), b.P2.InterpolateTo(b.P3, t)
+	ac, cd := a.InterpolateTo(c, t), c.InterpolateTo(d, t)
+	return ac.InterpolateTo(cd, t)
+}
+
+// Derivative returns the tangent of the curve at parameter t, whose length is
+// the speed at which the point moves as t increases.
+func (b CubicBezier) Derivative(t float32) Vec3 {
+	// The derivative is a quadratic Bézier curve through the differences of the
+	// control points
+	d0, d1, d2 := b.P1.Sub(b.P0), b.P2.Sub(b.P1), b.P3.Sub(b.P2)
+	s := 1 - t
+	return d0.Scale(3 * s * s).Add(d1.Scale(6 * s * t)).Add(d2.Scale(3 * t * t))
+}
+
+// SecondDerivative returns the rate of change of the tangent at parameter t.
+func (b CubicBezier) SecondDerivative(t float32) Vec3 {
+	e0 := b.P2.Sub(b.P1.Scale(2)).Add(b.P0)
+	e1 := b.P3.Sub(b.P2.Scale(2)).Add(b.P1)
+	return e0.Scale(6 * (1 - t)).Add(e1.Scale(6 * t))
+}
+
+// Split returns the two curves that together trace the same path, divided at
+// parameter t.
+func (b CubicBezier) Split(t float32) (CubicBezier, CubicBezier) {
+	a, c, d := b.P0.InterpolateTo(b.P1, t), b.P1.InterpolateTo(b.P2, t), b.P2.InterpolateTo(b.P3, t)
+	ac, cd := a.InterpolateTo(c, t), c.InterpolateTo(d, t)
+	mid := ac.InterpolateTo(cd, t)
+	return CubicBezier{b.P0, a, ac, mid}, CubicBezier{mid, cd, d, b.P3}
+}
+
+// Flatten returns points along the curve, such that the lines between them stray
+// no further than tolerance from the curve. Flat parts of the curve use fewer
+// points than tightly curved parts.
+func (b CubicBezier) Flatten(tolerance float32) []Vec3 {
+	points := []Vec3{b.P0}
+	return b.flatten(points, tolerance, 0)
+}
+
+func (b CubicBezier) flatten(points []Vec3, tolerance float32, depth int) []Vec3 {
+	// The curve lies inside the hull of its control points, so it is flat enough
+	// if the inner control points are close enough to the line between its ends
+	if depth >= maxSubdivisions ||
+		(distanceToSegment(b.P1, b.P0, b.P3) <= tolerance && distanceToSegment(b.P2, b.P0, b.P3) <= tolerance) {
+		return append(points, b.P3)
+	}
+	first, second := b.Split(0.5)
+	points = first.flatten(points, tolerance, depth+1)
+	return second.flatten(points, tolerance, depth+1)
+}
+
+// BSpline is a B-spline curve, which is smooth everywhere and only changes
+// locally when a control point is moved. If Weights are given, it is a
+// non-uniform rational B-spline (NURBS), which can represent conic sections
+// exactly.
+type BSpline struct {
+	Degree  int
+	Control []Vec3
+	// The non-decreasing parameter values where the polynomial pieces of the
+	// curve join. There must be len(Control) + Degree + 1 of them.
+	Knots []float32
+	// The weight of each control point, or nil if they are all 1.
+	Weights []float32
+}
+
+// UniformBSpline returns the B-spline of the given degree with evenly spaced
+// knots. The end knots are repeated so that the curve starts and ends at its
+// first and last control points. The curve's parameter goes from 0 to 1. It
+// returns an error unless the degree is at least 1 and there are more control
+// points than the degree.
+func UniformBSpline(degree int, control []Vec3) (*BSpline, error) {
+	if degree < 1 {
+		return nil, fmt.Errorf("invalid B-spline degree %d", degree)
+	}
+	n := len(control)
+	if n < degree+1 {
+		return nil, fmt.Errorf("B-spline of degree %d needs at least %d control points, found %d", degree, degree+1, n)
+	}
+	knots := make([]float32, n+degree+1)
+	spans := n - degree
+	for i := range knots {
+		switch {
+		case i <= degree:
+			knots[i] = 0
+		case i >= n:
+			knots[i] = 1
+		default:
+			knots[i] = float32(i-degree) / float32(spans)
+		}
+	}
+	return &BSpline{Degree: degree, Control: control, Knots: knots}, nil
+}
+
+// Domain returns the range of parameters over which the curve is defined.
+func (s *BSpline) Domain() (float32, float32) {
+	return s.Knots[s.Degree], s.Knots[len(s.Control)]
+}
+
+// At returns the point on the curve at parameter t, which is clamped to the
+// curve's domain.
+func (s *BSpline) At(t float32) Vec3 {
+	p := deBoor(s.Degree, s.homogeneous(), s.Knots, t)
+	return p.point()
+}
+
+// Derivative returns the tangent of the curve at parameter t.
+func (s *BSpline) Derivative(t float32) Vec3 {
+	if s.Degree == 0 {
+		return Vec3{}
+	}
+	control := s.homogeneous()
+	p := deBoor(s.Degree, control, s.Knots, t)
+	dp := deBoor(s.Degree-1, derivativeControl(s.Degree, control, s.Knots), s.Knots[1:len(s.Knots)-1], t)
+
+	// For a rational curve, C = A/w, so C' = (A' - w'C) / w
+	return dp.v.Sub(p.point().Scale(dp.w)).Scale(1 / p.w)
+}
+
+// Flatten returns points along the curve, such that the lines between them stray
+// no further than tolerance from the curve.
+func (s *BSpline) Flatten(tolerance float32) []Vec3 {
+	start, end := s.Domain()
+	points := []Vec3{s.At(start)}
+
+	// Each span between knots is a separate polynomial piece, so they are
+	// flattened separately
+	for i := s.Degree; i < len(s.Control); i++ {
+		t0, t1 := max32(s.Knots[i], start), min32(s.Knots[i+1], end)
+		if t1 > t0 {
+			points = flattenParametric(s.At, t0, t1, points, tolerance, 0)
+		}
+	}
+	return points
+}
+
+// Returns the control points in homogeneous coordinates, scaled by their
+// weights.
+func (s *BSpline) homogeneous() []hpoint {
+	control := make([]hpoint, len(s.Control))
+	for i, c := range s.Control {
+		w := float32(1)
+		if s.Weights != nil {
+			w = s.Weights[i]
+		}
+		control[i] = hpoint{v: c.Scale(w), w: w}
+	}
+	return control
+}
+
+// hpoint is a point in homogeneous coordinates.
+type hpoint struct {
+	v Vec3
+	w float32
+}
+
+func (p hpoint) point() Vec3 {
+	return p.v.Scale(1 / p.w)
+}
+
+func (p hpoint) interpolateTo(q hpoint, alpha float32) hpoint {
+	return hpoint{v: p.v.InterpolateTo(q.v, alpha), w: p.w + (q.w-p.w)*alpha}
+}
+
+// deBoor evaluates the B-spline with the given control points and knots at t.
+func deBoor(degree int, control []hpoint, knots []float32, t float32) hpoint {
+	n := len(control)
+	t = max32(knots[degree], min32(t, knots[n]))
+
+	// Find the span of knots containing t
+	span := degree
+	for span < n-1 && t >= knots[span+1] {
+		span++
+	}
+
+	d := make([]hpoint, degree+1)
+	copy(d, control[span-degree:span+1])
+	for r := 1; r <= degree; r++ {
+		for j := degree; j >= r; j-- {
+			i := span - degree + j
+			denom := knots[i+degree+1-r] - knots[i]
+			var alpha float32
+			if denom != 0 {
+				alpha = (t - knots[i]) / denom
+			}
+			d[j] = d[j-1].interpolateTo(d[j], alpha)
+		}
+	}
+	return d[degree]
+}
+
+// derivativeControl returns the control points of the derivative of the
+// B-spline, which has one degree lower and uses the knots without their ends.
+func derivativeControl(degree int, control []hpoint, knots []float32) []hpoint {
+	derived := make([]hpoint, len(control)-1)
+	for i := range derived {
+		denom := knots[i+degree+1] - knots[i+1]
+		if denom == 0 {
+			continue
+		}
+		scale := float32(degree) / denom
+		derived[i] = hpoint{
+			v: control[i+1].v.Sub(control[i].v).Scale(scale),
+			w: (control[i+1].w - control[i].w) * scale,
+		}
+	}
+	return derived
+}
+
+// flattenParametric appends points along the curve f between t0 and t1, not
+// including f(t0), subdividing until each line is within tolerance of the curve.
+func flattenParametric(f func(float32) Vec3, t0, t1 float32, points []Vec3, tolerance float32, depth int) []Vec3 {
+	start, end := f(t0), f(t1)
+
+	// Sample a few points in between, as a single midpoint can lie on the line
+	// even when the curve does not
+	flat := true
+	for _, alpha := range []float32{0.25, 0.5, 0.75} {
+		if distanceToSegment(f(t0+(t1-t0)*alpha), start, end) > tolerance {
+			flat = false
+			break
+		}
+	}
+	if flat || depth >= maxSubdivisions {
+		return append(points, end)
+	}
+
+	mid := (t0 + t1) / 2
+	points = flattenParametric(f, t0, mid, points, tolerance, depth+1)
+	return flattenParametric(f, mid, t1, points, tolerance, depth+1)
+}
+
+// distanceToSegment returns the distance from p to the closest point on the
+// line segment between a and b.
+func distanceToSegment(p, a, b Vec3) float32 {
+	ab := b.Sub(a)
+	lengthSquared := ab.LengthSquared()
+	if lengthSquared == 0 {
+		return p.Distance(a)
+	}
+	t := max32(0, min32(1, p.Sub(a).Dot(ab)/lengthSquared))
+	return p.Distance(a.Add(ab.Scale(t)))
+}
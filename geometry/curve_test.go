@@ -0,0 +1,37 @@
+package geometry
+
+import "testing"
+
+func TestUniformBSpline(t *testing.T) {
+	control := []Vec3{{}, {X: 1, Y: 2}, {X: 2, Y: -1}, {X: 3}}
+	tests := []struct {
+		name    string
+		degree  int
+		control []Vec3
+		wantErr bool
+	}{
+		{"cubic", 3, control, false},
+		{"linear", 1, control[:2], false},
+		{"too few control points", 3, control[:3], true},
+		{"no control points", 1, nil, true},
+		{"zero degree", 0, control, true},
+	}
+	for _, tt := range tests {
+		s, err := UniformBSpline(tt.degree, tt.control)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: UniformBSpline() error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		// Clamped knots make the curve start and end at its end points
+		first, last := tt.control[0], tt.control[len(tt.control)-1]
+		if got := s.At(0); !got.ApproxEqual(first, epsilon) {
+			t.Errorf("%s: At(0) = %v, want %v", tt.name, got, first)
+		}
+		if got := s.At(1); !got.ApproxEqual(last, epsilon) {
+			t.Errorf("%s: At(1) = %v, want %v", tt.name, got, last)
+		}
+	}
+}
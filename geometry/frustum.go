@@ -0,0 +1,102 @@
+package geometry
+
+import "math"
+
+// Plane is the set of points p where Normal.Dot(p) + D = 0. Points on the side
+// that Normal points to are in front of the plane.
+type Plane struct {
+	Normal Vec3
+	D      float32
+}
+
+// Distance returns the signed distance from the plane to p, which is positive
+// in front of the plane. The plane's normal must have unit length.
+func (p Plane) Distance(v Vec3) float32 {
+	return p.Normal.Dot(v) + p.D
+}
+
+// Frustum is the volume of space that can be seen by the camera, bounded by
+// planes that face inwards.
+type Frustum struct {
+	Planes []Plane
+}
+
+// PerspectiveFrustum returns the frustum of a camera at the origin looking
+// along the Z-axis, which sees points where |X/Z| <= tanHalfX, |Y/Z| <= tanHalfY
+// and near <= Z <= far. far can be infinite.
+func PerspectiveFrustum(tanHalfX, tanHalfY, near, far float32) Frustum {
+	side := func(normal Vec3) Plane {
+		return Plane{Normal: normal.Normalize()}
+	}
+	planes := []Plane{
+		side(Vec3{X: 1, Z: tanHalfX}),
+		side(Vec3{X: -1, Z: tanHalfX}),
+		side(Vec3{Y: 1, Z: tanHalfY}),
+		side(Vec3{Y: -1, Z: tanHalfY}),
+		{Normal: Vec3{Z: 1}, D: -near},
+	}
+	if !math.IsInf(float64(far), 1) {
+		planes = append(planes, Plane{Normal: Vec3{Z: -1}, D: far})
+	}
+	return Frustum{Planes: planes}
+}
+
+// IntersectsSphere returns whether any part of the sphere may be inside the
+// frustum. Spheres near the corners of the frustum can be falsely reported as
+// intersecting, but spheres inside it never are reported as outside.
+func (f Frustum) IntersectsSphere(s Sphere) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(s.Center) < -s.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsAABB returns whether any part of the box may be inside the frustum,
+// with the same caveat as IntersectsSphere.
+func (f Frustum) IntersectsAABB(b AABB) bool {
+	for _, plane := range f.Planes {
+		// The corner of the box furthest in front of the plane
+		corner := b.Min
+		if plane.Normal.X >= 0 {
+			corner.X = b.Max.X
+		}
+		if plane.Normal.Y >= 0 {
+			corner.Y = b.Max.Y
+		}
+		if plane.Normal.Z >= 0 {
+			corner.Z = b.Max.Z
+		}
+		if plane.Distance(corner) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FrustumFromMatrix returns the frustum of points in front of the plane
+// Z = near that the projection matrix m maps to the view, where X/W and Y/W are
+// between -1 and 1.
+func FrustumFromMatrix(m *Mat4, near float32) Frustum {
+	// Each side of the frustum is where W = X, W = -X, W = Y or W = -Y after
+	// transforming by m, which is a plane whose coefficients combine the rows of
+	// the matrix
+	side := func(sign float32, row int) Plane {
+		normal := Vec3{
+			X: m[3][0] + sign*m[row][0],
+			Y: m[3][1] + sign*m[row][1],
+			Z: m[3][2] + sign*m[row][2],
+		}
+		d := m[3][3] + sign*m[row][3]
+		length := normal.Length()
+		return Plane{Normal: normal.Scale(1 / length), D: d / length}
+	}
+	return Frustum{Planes: []Plane{
+		side(1, 0),
+		side(-1, 0),
+		side(1, 1),
+		side(-1, 1),
+		{Normal: Vec3{Z: 1}, D: -near},
+	}}
+}
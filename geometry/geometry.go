@@ -23,6 +23,73 @@ func (m *Mat3) VecMul(v Vec3) Vec3 {
 	}
 }
 
+// Mat3Identity returns the identity matrix.
+func Mat3Identity() *Mat3 {
+	return &Mat3{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+	}
+}
+
+// Scaling returns the matrix that scales each axis by the corresponding
+// component of s.
+func Scaling(s Vec3) *Mat3 {
+	return &Mat3{
+		{s.X, 0, 0},
+		{0, s.Y, 0},
+		{0, 0, s.Z},
+	}
+}
+
+// Transpose returns the matrix with its rows and columns swapped. For a
+// rotation matrix, this is the opposite rotation.
+func (m *Mat3) Transpose() *Mat3 {
+	var t Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t[i][j] = m[j][i]
+		}
+	}
+	return &t
+}
+
+// Determinant returns the determinant of the matrix.
+func (m *Mat3) Determinant() float32 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Inverse returns the inverse of the matrix, or false if it is singular.
+func (m *Mat3) Inverse() (*Mat3, bool) {
+	det := m.Determinant()
+	if det == 0 {
+		return nil, false
+	}
+
+	// The inverse is the transpose of the matrix of cofactors, divided by the
+	// determinant
+	invDet := 1 / det
+	return &Mat3{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) * invDet,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) * invDet,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) * invDet,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) * invDet,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) * invDet,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) * invDet,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) * invDet,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) * invDet,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) * invDet,
+		},
+	}, true
+}
+
 // MatMul returns the matrix product of matriv with n.
 func (m *Mat3) MatMul(n *Mat3) *Mat3 {
 	var product Mat3
@@ -70,3 +137,46 @@ func sin(x float32) float32 {
 func cos(x float32) float32 {
 	return float32(math.Cos(float64(x)))
 }
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs32(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// LookRotation returns the rotation matrix that maps vectors into a space whose
+// Z-axis points along forward and whose Y-axis is as close to up as possible.
+func LookRotation(forward, up Vec3) *Mat3 {
+	forward = forward.Normalize()
+	right := up.Cross(forward)
+	if right.Length() == 0 {
+		// Any up vector will do if forward is parallel to it
+		right = Vec3{X: 0, Y: 0, Z: 1}.Cross(forward)
+		if right.Length() == 0 {
+			right = Vec3{X: 1, Y: 0, Z: 0}
+		}
+	}
+	right = right.Normalize()
+	up = forward.Cross(right)
+
+	return &Mat3{
+		{right.X, right.Y, right.Z},
+		{up.X, up.Y, up.Z},
+		{forward.X, forward.Y, forward.Z},
+	}
+}
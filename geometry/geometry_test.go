@@ -0,0 +1,65 @@
+package geometry
+
+import "testing"
+
+func TestMat3Determinant(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *Mat3
+		want float32
+	}{
+		{"identity", Mat3Identity(), 1},
+		{"scaling", Scaling(Vec3{X: 2, Y: 3, Z: 4}), 24},
+		{"rotation", RotationY(0.7), 1},
+		{"singular", &Mat3{{1, 2, 3}, {2, 4, 6}, {0, 1, 1}}, 0},
+		{"general", &Mat3{{2, 0, 1}, {1, 3, 2}, {1, 1, 2}}, 6},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Determinant(); abs32(got-tt.want) > epsilon {
+			t.Errorf("%s: Determinant() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMat3Transpose(t *testing.T) {
+	m := &Mat3{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	want := Mat3{{1, 4, 7}, {2, 5, 8}, {3, 6, 9}}
+	if got := m.Transpose(); *got != want {
+		t.Errorf("Transpose() = %v, want %v", *got, want)
+	}
+	if got := m.Transpose().Transpose(); *got != *m {
+		t.Errorf("Transpose().Transpose() = %v, want %v", *got, *m)
+	}
+}
+
+func TestMat3Inverse(t *testing.T) {
+	tests := []struct {
+		name   string
+		m      *Mat3
+		wantOK bool
+	}{
+		{"identity", Mat3Identity(), true},
+		{"scaling", Scaling(Vec3{X: 2, Y: 0.5, Z: 4}), true},
+		{"rotation", RotationX(1.2).MatMul(RotationZ(-0.4)), true},
+		{"general", &Mat3{{2, 0, 1}, {1, 3, 2}, {1, 1, 2}}, true},
+		{"singular", &Mat3{{1, 2, 3}, {2, 4, 6}, {0, 1, 1}}, false},
+		{"zero", &Mat3{}, false},
+	}
+	for _, tt := range tests {
+		inv, ok := tt.m.Inverse()
+		if ok != tt.wantOK {
+			t.Errorf("%s: Inverse() ok = %v, want %v", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if !ok {
+			if inv != nil {
+				t.Errorf("%s: Inverse() = %v, want nil", tt.name, *inv)
+			}
+			continue
+		}
+		v := Vec3{X: 1, Y: -2, Z: 3}
+		if got := inv.VecMul(tt.m.VecMul(v)); !got.ApproxEqual(v, epsilon) {
+			t.Errorf("%s: inverse applied after matrix gives %v, want %v", tt.name, got, v)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package geometry
+
+import "math"
+
+// Mat4 is a 4x4 matrix representing a transform of points in 3D, in
+// homogeneous coordinates. It acts on points as column vectors, with an
+// implicit W-component of 1. Transforms other than projections leave the bottom
+// row as (0, 0, 0, 1).
+type Mat4 [4][4]float32
+
+// Mat4Identity returns the identity transform.
+func Mat4Identity() *Mat4 {
+	return &Mat4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Translation returns the transform that moves points by t.
+func Translation(t Vec3) *Mat4 {
+	m := Mat4Identity()
+	m[0][3], m[1][3], m[2][3] = t.X, t.Y, t.Z
+	return m
+}
+
+// Mat4FromMat3 returns the transform that applies the linear map m to points.
+func Mat4FromMat3(m *Mat3) *Mat4 {
+	n := Mat4Identity()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			n[i][j] = m[i][j]
+		}
+	}
+	return n
+}
+
+// TRS returns the transform that scales points by s, then rotates them by r,
+// and then moves them by t.
+func TRS(t Vec3, r Quat, s Vec3) *Mat4 {
+	m := Mat4FromMat3(r.Mat3().MatMul(Scaling(s)))
+	m[0][3], m[1][3], m[2][3] = t.X, t.Y, t.Z
+	return m
+}
+
+// MatMul returns the matrix product of the matrix with n, which is the
+// transform n followed by m.
+func (m *Mat4) MatMul(n *Mat4) *Mat4 {
+	var result Mat4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				result[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return &result
+}
+
+// TransformPoint returns the point v transformed by the matrix.
+func (m *Mat4) TransformPoint(v Vec3) Vec3 {
+	return m.TransformDirection(v).Add(m.Translation())
+}
+
+// TransformDirection returns the direction v transformed by the matrix, which
+// ignores its translation.
+func (m *Mat4) TransformDirection(v Vec3) Vec3 {
+	return m.Mat3().VecMul(v)
+}
+
+// Translation returns how far the matrix moves the origin.
+func (m *Mat4) Translation() Vec3 {
+	return Vec3{X: m[0][3], Y: m[1][3], Z: m[2][3]}
+}
+
+// Mat3 returns the linear part of the transform, without its translation.
+func (m *Mat4) Mat3() *Mat3 {
+	return &Mat3{
+		{m[0][0], m[0][1], m[0][2]},
+		{m[1][0], m[1][1], m[1][2]},
+		{m[2][0], m[2][1], m[2][2]},
+	}
+}
+
+// MulVec4 returns the product of the matrix with the homogeneous vector (v, w).
+func (m *Mat4) MulVec4(v Vec3, w float32) (Vec3, float32) {
+	product := Vec3{
+		X: m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z + m[0][3]*w,
+		Y: m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z + m[1][3]*w,
+		Z: m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z + m[2][3]*w,
+	}
+	return product, m[3][0]*v.X + m[3][1]*v.Y + m[3][2]*v.Z + m[3][3]*w
+}
+
+// Affine returns whether the bottom row of the matrix is (0, 0, 0, 1), so that
+// it does not apply perspective.
+func (m *Mat4) Affine() bool {
+	return m[3] == [4]float32{0, 0, 0, 1}
+}
+
+// Inverse returns the inverse of the matrix, or false if it is singular.
+func (m *Mat4) Inverse() (*Mat4, bool) {
+	// Gauss-Jordan elimination, reducing m to the identity while applying the
+	// same row operations to inv
+	var a, inv [4][4]float64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			a[i][j] = float64(m[i][j])
+		}
+		inv[i][i] = 1
+	}
+
+	for col := 0; col < 4; col++ {
+		// Use the largest remaining entry in the column as the pivot, for accuracy
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := 1 / a[col][col]
+		for j := 0; j < 4; j++ {
+			a[col][j] *= scale
+			inv[col][j] *= scale
+		}
+		for row := 0; row < 4; row++ {
+			if row == col || a[row][col] == 0 {
+				continue
+			}
+			factor := a[row][col]
+			for j := 0; j < 4; j++ {
+				a[row][j] -= factor * a[col][j]
+				inv[row][j] -= factor * inv[col][j]
+			}
+		}
+	}
+
+	var result Mat4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			result[i][j] = float32(inv[i][j])
+		}
+	}
+	return &result, true
+}
@@ -0,0 +1,53 @@
+package geometry
+
+// BezierPatch is a bicubic Bézier surface defined by a 4x4 grid of control
+// points, indexed by row along V and then column along U. The surface passes
+// through the four corner points.
+type BezierPatch [4][4]Vec3
+
+// At returns the point on the surface at parameters (u, v), between 0 and 1.
+func (p *BezierPatch) At(u, v float32) Vec3 {
+	return p.column(u).At(v)
+}
+
+// Derivatives returns the tangents of the surface along U and V at (u, v).
+func (p *BezierPatch) Derivatives(u, v float32) (Vec3, Vec3) {
+	return p.row(v).Derivative(u), p.column(u).Derivative(v)
+}
+
+// Normal returns the unit normal of the surface at (u, v), which is the cross
+// product of the tangents along U and V. Where a tangent vanishes, such as at a
+// point where the edges of the patch meet, the normal is found by moving
+// slightly into the patch.
+func (p *BezierPatch) Normal(u, v float32) Vec3 {
+	const nudge = 1e-3
+
+	du, dv := p.Derivatives(u, v)
+	normal := du.Cross(dv)
+	if normal.LengthSquared() > 1e-12 {
+		return normal.Normalize()
+	}
+
+	u += nudge * (0.5 - u)
+	v += nudge * (0.5 - v)
+	du, dv = p.Derivatives(u, v)
+	return du.Cross(dv).Normalize()
+}
+
+// Returns the curve through the surface at u, running along V.
+func (p *BezierPatch) column(u float32) CubicBezier {
+	var points [4]Vec3
+	for i := range points {
+		points[i] = CubicBezier{p[i][0], p[i][1], p[i][2], p[i][3]}.At(u)
+	}
+	return CubicBezier{points[0], points[1], points[2], points[3]}
+}
+
+// Returns the curve through the surface at v, running along U.
+func (p *BezierPatch) row(v float32) CubicBezier {
+	var points [4]Vec3
+	for j := range points {
+		points[j] = CubicBezier{p[0][j], p[1][j], p[2][j], p[3][j]}.At(v)
+	}
+	return CubicBezier{points[0], points[1], points[2], points[3]}
+}
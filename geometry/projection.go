@@ -0,0 +1,186 @@
+package geometry
+
+import "math"
+
+// Projection maps points in the viewer's space onto the view plane.
+type Projection interface {
+	// Project returns the position of v on the view plane, where visible points
+	// have X and Y between -1 and 1. Z holds the depth of the point, which for
+	// projections that are not affine is also what X and Y were divided by.
+	Project(v Vec3) Vec3
+	// Matrix returns the projection in homogeneous coordinates. Dividing the
+	// X- and Y-components of a transformed point by its W-component gives the
+	// same position as Project.
+	Matrix() *Mat4
+	// Affine returns whether the projection keeps parallel lines parallel, in
+	// which case attributes should be interpolated linearly across the screen.
+	Affine() bool
+}
+
+// Perspective is a projection where things further from the viewer appear
+// smaller, as seen by a camera at the origin looking along the Z-axis.
+type Perspective struct {
+	// The vertical field of view, in radians.
+	FOV float32
+	// The width of the view divided by its height.
+	Aspect float32
+}
+
+// Project returns the perspective projection of v.
+func (p Perspective) Project(v Vec3) Vec3 {
+	projected := Project(v, p.focalLength())
+	projected.X /= p.Aspect
+	return projected
+}
+
+// Matrix returns the projection in homogeneous coordinates, which stores 1/Z
+// in the Z-component after dividing by W.
+func (p Perspective) Matrix() *Mat4 {
+	d := p.focalLength()
+	return &Mat4{
+		{d / p.Aspect, 0, 0, 0},
+		{0, d, 0, 0},
+		{0, 0, 0, 1},
+		{0, 0, 1, 0},
+	}
+}
+
+// Affine returns false.
+func (p Perspective) Affine() bool {
+	return false
+}
+
+// Returns the distance to the view plane at which its height is 2.
+func (p Perspective) focalLength() float32 {
+	return 1 / float32(math.Tan(float64(p.FOV/2)))
+}
+
+// Orthographic is a projection along the Z-axis, where things appear the same
+// size regardless of their distance. The view shows points between Left and
+// Right, and between Bottom and Top.
+type Orthographic struct {
+	Left, Right, Bottom, Top float32
+}
+
+// Project returns the orthographic projection of v.
+func (o Orthographic) Project(v Vec3) Vec3 {
+	return projectMatrix(o.Matrix(), v)
+}
+
+// Matrix returns the projection in homogeneous coordinates, which keeps the
+// Z-component unchanged.
+func (o Orthographic) Matrix() *Mat4 {
+	width, height := o.Right-o.Left, o.Top-o.Bottom
+	return &Mat4{
+		{2 / width, 0, 0, -(o.Right + o.Left) / width},
+		{0, 2 / height, 0, -(o.Top + o.Bottom) / height},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Affine returns true.
+func (o Orthographic) Affine() bool {
+	return true
+}
+
+// Oblique is a parallel projection where depth is shown by shifting points
+// sideways in proportion to their distance behind the plane Z = Plane, before
+// projecting them orthographically.
+type Oblique struct {
+	View Orthographic
+	// The direction in which points are shifted, in radians anticlockwise from
+	// the positive X-axis.
+	Angle float32
+	// How far points are shifted for every unit of depth. A cavalier projection
+	// uses 1, and a cabinet projection uses 0.5.
+	Depth float32
+	Plane float32
+}
+
+// Cabinet returns an oblique projection where depth is shown at half scale,
+// receding up and to the right at 45 degrees.
+func Cabinet(view Orthographic, plane float32) Oblique {
+	return Oblique{View: view, Angle: math.Pi / 4, Depth: 0.5, Plane: plane}
+}
+
+// Project returns the oblique projection of v.
+func (o Oblique) Project(v Vec3) Vec3 {
+	return projectMatrix(o.Matrix(), v)
+}
+
+// Matrix returns the projection in homogeneous coordinates, which keeps the
+// Z-component unchanged.
+func (o Oblique) Matrix() *Mat4 {
+	dx, dy := o.Depth*cos(o.Angle), o.Depth*sin(o.Angle)
+	shear := &Mat4{
+		{1, 0, dx, -dx * o.Plane},
+		{0, 1, dy, -dy * o.Plane},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+	return o.View.Matrix().MatMul(shear)
+}
+
+// Affine returns true.
+func (o Oblique) Affine() bool {
+	return true
+}
+
+// MatrixProjection is a projection given by an arbitrary matrix. Visible points
+// must have a positive W-component after being transformed. If the matrix is
+// affine, the transformed Z-component is used as depth, and otherwise W is.
+type MatrixProjection struct {
+	M Mat4
+}
+
+// Project returns the projection of v by the matrix.
+func (p MatrixProjection) Project(v Vec3) Vec3 {
+	return projectMatrix(&p.M, v)
+}
+
+// Matrix returns the matrix of the projection.
+func (p MatrixProjection) Matrix() *Mat4 {
+	return &p.M
+}
+
+// Affine returns whether the matrix is affine.
+func (p MatrixProjection) Affine() bool {
+	return p.M.Affine()
+}
+
+// projectMatrix transforms v by m, and divides X and Y by W. The depth is Z for
+// affine matrices and W otherwise.
+func projectMatrix(m *Mat4, v Vec3) Vec3 {
+	clip, w := m.MulVec4(v, 1)
+	depth := w
+	if m.Affine() {
+		depth = clip.Z
+	}
+	return Vec3{X: clip.X / w, Y: clip.Y / w, Z: depth}
+}
+
+// UnprojectRay returns the ray of points that the projection maps to (x, y) on
+// the view plane. The ray starts on the plane Z = 0 and its direction has a
+// Z-component of 1, so the position of a point along the ray is its depth.
+func UnprojectRay(p Projection, x, y float32) Ray {
+	inv, ok := p.Matrix().Inverse()
+	if !ok {
+		return Ray{Dir: Vec3{Z: 1}}
+	}
+
+	// Find two points on the ray, at different depths. A Z-component of 0 can
+	// be infinitely far away, so we avoid it.
+	unproject := func(z float32) Vec3 {
+		v, w := inv.MulVec4(Vec3{X: x, Y: y, Z: z}, 1)
+		return v.Scale(1 / w)
+	}
+	near, far := unproject(1), unproject(0.5)
+
+	dir := far.Sub(near)
+	if dir.Z == 0 {
+		return Ray{Origin: near, Dir: dir}
+	}
+	dir = dir.Scale(1 / dir.Z)
+	return Ray{Origin: near.Sub(dir.Scale(near.Z)), Dir: dir}
+}
@@ -0,0 +1,179 @@
+package geometry
+
+import "math"
+
+// Quat is a quaternion X*i + Y*j + Z*k + W. Unit quaternions represent
+// rotations, and unlike Euler angles can be composed and interpolated without
+// suffering from gimbal lock.
+type Quat struct {
+	X, Y, Z, W float32
+}
+
+// QuatIdentity returns the quaternion representing no rotation.
+func QuatIdentity() Quat {
+	return Quat{W: 1}
+}
+
+// QuatFromAxisAngle returns the rotation by theta around axis, in the same
+// direction as RotationX, RotationY and RotationZ for the coordinate axes.
+func QuatFromAxisAngle(axis Vec3, theta float32) Quat {
+	axis = axis.Normalize().Scale(sin(theta / 2))
+	return Quat{X: axis.X, Y: axis.Y, Z: axis.Z, W: cos(theta / 2)}
+}
+
+// QuatFromEuler returns the rotation equivalent to
+// RotationX(x).MatMul(RotationY(y)).MatMul(RotationZ(z)), which rotates around
+// the Z-axis first and the X-axis last.
+func QuatFromEuler(x, y, z float32) Quat {
+	qx := QuatFromAxisAngle(Vec3{X: 1}, x)
+	qy := QuatFromAxisAngle(Vec3{Y: 1}, y)
+	qz := QuatFromAxisAngle(Vec3{Z: 1}, z)
+	return qx.Mul(qy).Mul(qz)
+}
+
+// QuatFromMat3 returns the rotation represented by the rotation matrix m.
+func QuatFromMat3(m *Mat3) Quat {
+	// Use the largest of the diagonal terms to avoid dividing by a small number
+	var q Quat
+	trace := m[0][0] + m[1][1] + m[2][2]
+	switch {
+	case trace > 0:
+		s := 2 * sqrt(trace+1)
+		q = Quat{
+			W: s / 4,
+			X: (m[2][1] - m[1][2]) / s,
+			Y: (m[0][2] - m[2][0]) / s,
+			Z: (m[1][0] - m[0][1]) / s,
+		}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = Quat{
+			W: (m[2][1] - m[1][2]) / s,
+			X: s / 4,
+			Y: (m[0][1] + m[1][0]) / s,
+			Z: (m[0][2] + m[2][0]) / s,
+		}
+	case m[1][1] > m[2][2]:
+		s := 2 * sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = Quat{
+			W: (m[0][2] - m[2][0]) / s,
+			X: (m[0][1] + m[1][0]) / s,
+			Y: s / 4,
+			Z: (m[1][2] + m[2][1]) / s,
+		}
+	default:
+		s := 2 * sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = Quat{
+			W: (m[1][0] - m[0][1]) / s,
+			X: (m[0][2] + m[2][0]) / s,
+			Y: (m[1][2] + m[2][1]) / s,
+			Z: s / 4,
+		}
+	}
+	return q.Normalize()
+}
+
+// Mul returns the product qr, which is the rotation r followed by q.
+func (q Quat) Mul(r Quat) Quat {
+	return Quat{
+		X: q.W*r.X + q.X*r.W + q.Y*r.Z - q.Z*r.Y,
+		Y: q.W*r.Y - q.X*r.Z + q.Y*r.W + q.Z*r.X,
+		Z: q.W*r.Z + q.X*r.Y - q.Y*r.X + q.Z*r.W,
+		W: q.W*r.W - q.X*r.X - q.Y*r.Y - q.Z*r.Z,
+	}
+}
+
+// Dot returns the dot product of the quaternion with r.
+func (q Quat) Dot(r Quat) float32 {
+	return q.X*r.X + q.Y*r.Y + q.Z*r.Z + q.W*r.W
+}
+
+// Length returns the norm of the quaternion.
+func (q Quat) Length() float32 {
+	return sqrt(q.Dot(q))
+}
+
+// Normalize returns the unit quaternion in the direction of q. Rotations
+// should be normalized after being composed many times, to correct for
+// accumulated rounding errors. The zero quaternion becomes the identity.
+func (q Quat) Normalize() Quat {
+	length := q.Length()
+	if length == 0 {
+		return QuatIdentity()
+	}
+	return q.scale(1 / length)
+}
+
+// Conjugate returns the quaternion with its vector part negated. For a unit
+// quaternion, this is the opposite rotation.
+func (q Quat) Conjugate() Quat {
+	return Quat{X: -q.X, Y: -q.Y, Z: -q.Z, W: q.W}
+}
+
+// Inverse returns the quaternion r such that qr is the identity.
+func (q Quat) Inverse() Quat {
+	return q.Conjugate().scale(1 / q.Dot(q))
+}
+
+// Rotate returns v rotated by the unit quaternion q.
+func (q Quat) Rotate(v Vec3) Vec3 {
+	// Equivalent to q * v * q^-1, expanded to avoid the full products
+	u := Vec3{X: q.X, Y: q.Y, Z: q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+// Mat3 returns the rotation matrix equivalent to the unit quaternion q.
+func (q Quat) Mat3() *Mat3 {
+	x, y, z, w := q.X, q.Y, q.Z, q.W
+	return &Mat3{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// Nlerp interpolates between the rotations q and r by step alpha, by linearly
+// interpolating and normalizing. It is cheaper than Slerp, but does not rotate
+// at a constant speed.
+func (q Quat) Nlerp(r Quat, alpha float32) Quat {
+	// Take the shorter path around the sphere
+	if q.Dot(r) < 0 {
+		r = r.scale(-1)
+	}
+	return q.scale(1 - alpha).add(r.scale(alpha)).Normalize()
+}
+
+// Slerp interpolates between the rotations q and r by step alpha, rotating at
+// a constant speed along the shortest path.
+func (q Quat) Slerp(r Quat, alpha float32) Quat {
+	cosTheta := q.Dot(r)
+	// Take the shorter path around the sphere
+	if cosTheta < 0 {
+		r = r.scale(-1)
+		cosTheta = -cosTheta
+	}
+
+	// For nearly identical rotations, sin(theta) is too small to divide by
+	if cosTheta > 0.9995 {
+		return q.Nlerp(r, alpha)
+	}
+
+	theta := float32(math.Acos(float64(cosTheta)))
+	sinTheta := sin(theta)
+	a := sin((1-alpha)*theta) / sinTheta
+	b := sin(alpha*theta) / sinTheta
+	return q.scale(a).add(r.scale(b))
+}
+
+func (q Quat) scale(k float32) Quat {
+	return Quat{X: k * q.X, Y: k * q.Y, Z: k * q.Z, W: k * q.W}
+}
+
+func (q Quat) add(r Quat) Quat {
+	return Quat{X: q.X + r.X, Y: q.Y + r.Y, Z: q.Z + r.Z, W: q.W + r.W}
+}
+
+func sqrt(x float32) float32 {
+	return float32(math.Sqrt(float64(x)))
+}
@@ -0,0 +1,104 @@
+package geometry
+
+import "math"
+
+// Ray is a half-line starting at Origin and extending in direction Dir. Points
+// along the ray are Origin + t*Dir for t >= 0.
+type Ray struct {
+	Origin Vec3
+	Dir    Vec3
+}
+
+// At returns the point at distance t along the ray, measured in multiples of
+// the length of Dir.
+func (r Ray) At(t float32) Vec3 {
+	return r.Origin.Add(r.Dir.Scale(t))
+}
+
+// IntersectTriangle returns where the ray hits the triangle, using the
+// Möller–Trumbore algorithm. t is the position along the ray, and u and v are
+// the barycentric weights of v1 and v2 at the hit point. Both sides of the
+// triangle can be hit.
+func (r Ray) IntersectTriangle(v0, v1, v2 Vec3) (t, u, v float32, ok bool) {
+	const epsilon = 1e-7
+
+	edge1, edge2 := v1.Sub(v0), v2.Sub(v0)
+	p := r.Dir.Cross(edge2)
+	det := edge1.Dot(p)
+	// The ray is parallel to the triangle
+	if abs32(det) < epsilon {
+		return 0, 0, 0, false
+	}
+	invDet := 1 / det
+
+	s := r.Origin.Sub(v0)
+	u = s.Dot(p) * invDet
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+
+	q := s.Cross(edge1)
+	v = r.Dir.Dot(q) * invDet
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+
+	t = edge2.Dot(q) * invDet
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, u, v, true
+}
+
+// IntersectAABB returns the positions along the ray where it enters and leaves
+// the box. If the ray starts inside the box, tNear is 0.
+func (r Ray) IntersectAABB(b AABB) (tNear, tFar float32, ok bool) {
+	tNear, tFar = 0, float32(math.Inf(1))
+
+	// Clip the ray against each pair of parallel planes of the box in turn
+	origin := [3]float32{r.Origin.X, r.Origin.Y, r.Origin.Z}
+	dir := [3]float32{r.Dir.X, r.Dir.Y, r.Dir.Z}
+	min := [3]float32{b.Min.X, b.Min.Y, b.Min.Z}
+	max := [3]float32{b.Max.X, b.Max.Y, b.Max.Z}
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if origin[i] < min[i] || origin[i] > max[i] {
+				return 0, 0, false
+			}
+			continue
+		}
+		t0, t1 := (min[i]-origin[i])/dir[i], (max[i]-origin[i])/dir[i]
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		tNear, tFar = max32(tNear, t0), min32(tFar, t1)
+		if tNear > tFar {
+			return 0, 0, false
+		}
+	}
+	return tNear, tFar, true
+}
+
+// IntersectSphere returns the position along the ray where it first hits the
+// sphere. If the ray starts inside the sphere, it is where the ray leaves it.
+func (r Ray) IntersectSphere(s Sphere) (t float32, ok bool) {
+	// Solve |Origin + t*Dir - Center|^2 = Radius^2 for t
+	oc := r.Origin.Sub(s.Center)
+	a := r.Dir.Dot(r.Dir)
+	halfB := oc.Dot(r.Dir)
+	c := oc.Dot(oc) - s.Radius*s.Radius
+	discriminant := halfB*halfB - a*c
+	if a == 0 || discriminant < 0 {
+		return 0, false
+	}
+
+	root := sqrt(discriminant)
+	t = (-halfB - root) / a
+	if t < 0 {
+		t = (-halfB + root) / a
+	}
+	if t < 0 {
+		return 0, false
+	}
+	return t, true
+}
@@ -1,5 +1,7 @@
 package geometry
 
+import "math"
+
 // Vec2 represents a point or vector in two-dimensional space.
 type Vec2 struct {
 	X, Y float32
@@ -31,5 +33,96 @@ func (v Vec2) Add(u Vec2) Vec2 {
 
 // InterpolateTo interpolates the vector towards another vector u by step alpha.
 func (v Vec2) InterpolateTo(u Vec2, alpha float32) Vec2 {
-  return u.Sub(v).Scale(alpha).Add(v)
+	return u.Sub(v).Scale(alpha).Add(v)
+}
+
+// Dot returns the dot product of the vector with u.
+func (v Vec2) Dot(u Vec2) float32 {
+	return v.X*u.X + v.Y*u.Y
+}
+
+// Cross returns the Z-component of the cross product of the vector with u, as
+// if both were in the plane Z = 0. Its sign tells which side of v u lies on.
+func (v Vec2) Cross(u Vec2) float32 {
+	return v.X*u.Y - v.Y*u.X
+}
+
+// Length returns the Euclidean length of the vector.
+func (v Vec2) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// LengthSquared returns the square of the vector's length, which is cheaper to
+// compute than Length.
+func (v Vec2) LengthSquared() float32 {
+	return v.Dot(v)
+}
+
+// Normalize returns the unit vector in the direction of v. The zero vector is
+// returned unchanged.
+func (v Vec2) Normalize() Vec2 {
+	length := v.Length()
+	if length == 0 {
+		return v
+	}
+	return v.Scale(1 / length)
+}
+
+// Distance returns the distance between the points v and u.
+func (v Vec2) Distance(u Vec2) float32 {
+	return u.Sub(v).Length()
+}
+
+// Negate returns vector -v.
+func (v Vec2) Negate() Vec2 {
+	return Vec2{X: -v.X, Y: -v.Y}
+}
+
+// Mul returns the component-wise product of the vector with u.
+func (v Vec2) Mul(u Vec2) Vec2 {
+	return Vec2{X: v.X * u.X, Y: v.Y * u.Y}
+}
+
+// Min returns the component-wise minimum of the vector and u.
+func (v Vec2) Min(u Vec2) Vec2 {
+	return Vec2{X: min32(v.X, u.X), Y: min32(v.Y, u.Y)}
+}
+
+// Max returns the component-wise maximum of the vector and u.
+func (v Vec2) Max(u Vec2) Vec2 {
+	return Vec2{X: max32(v.X, u.X), Y: max32(v.Y, u.Y)}
+}
+
+// Abs returns the vector with the absolute value of each component.
+func (v Vec2) Abs() Vec2 {
+	return Vec2{X: abs32(v.X), Y: abs32(v.Y)}
+}
+
+// Clamp returns the vector with each component clamped between the
+// corresponding components of lo and hi.
+func (v Vec2) Clamp(lo, hi Vec2) Vec2 {
+	return v.Max(lo).Min(hi)
+}
+
+// Reflect returns the vector reflected off a surface with unit normal n.
+func (v Vec2) Reflect(n Vec2) Vec2 {
+	return v.Sub(n.Scale(2 * v.Dot(n)))
+}
+
+// Refract returns the unit vector v refracted through a surface with unit
+// normal n, where eta is the ratio of the refractive indices on either side of
+// the surface. It returns false if there is total internal reflection.
+func (v Vec2) Refract(n Vec2, eta float32) (Vec2, bool) {
+	cosI := -v.Dot(n)
+	k := 1 - eta*eta*(1-cosI*cosI)
+	if k < 0 {
+		return Vec2{}, false
+	}
+	return v.Scale(eta).Add(n.Scale(eta*cosI - float32(math.Sqrt(float64(k))))), true
+}
+
+// ApproxEqual returns whether each component of the vector is within epsilon
+// of the corresponding component of u.
+func (v Vec2) ApproxEqual(u Vec2, epsilon float32) bool {
+	return abs32(v.X-u.X) <= epsilon && abs32(v.Y-u.Y) <= epsilon
 }
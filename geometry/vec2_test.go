@@ -0,0 +1,77 @@
+package geometry
+
+import "testing"
+
+func TestVec2Normalize(t *testing.T) {
+	tests := []struct {
+		v, want Vec2
+	}{
+		{Vec2{}, Vec2{}},
+		{Vec2{X: 3, Y: 4}, Vec2{X: 0.6, Y: 0.8}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Normalize(); !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%v.Normalize() = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestVec2Cross(t *testing.T) {
+	tests := []struct {
+		v, u Vec2
+		want float32
+	}{
+		{Vec2{X: 1}, Vec2{Y: 1}, 1},
+		{Vec2{Y: 1}, Vec2{X: 1}, -1},
+		{Vec2{X: 2, Y: 1}, Vec2{X: 4, Y: 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Cross(tt.u); got != tt.want {
+			t.Errorf("%v.Cross(%v) = %v, want %v", tt.v, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestVec2Reflect(t *testing.T) {
+	tests := []struct {
+		v, n, want Vec2
+	}{
+		{Vec2{X: 1, Y: -1}, Vec2{Y: 1}, Vec2{X: 1, Y: 1}},
+		{Vec2{X: -2}, Vec2{X: 1}, Vec2{X: 2}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Reflect(tt.n); !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%v.Reflect(%v) = %v, want %v", tt.v, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestVec2Refract(t *testing.T) {
+	diagonal := Vec2{X: 1, Y: -1}.Normalize()
+	tests := []struct {
+		name   string
+		v, n   Vec2
+		eta    float32
+		want   Vec2
+		wantOK bool
+	}{
+		{"head on", Vec2{Y: -1}, Vec2{Y: 1}, 1 / 1.5, Vec2{Y: -1}, true},
+		{"same medium", diagonal, Vec2{Y: 1}, 1, diagonal, true},
+		// sin(45°) / 1.5, with the cosine making up a unit vector
+		{"into denser medium", diagonal, Vec2{Y: 1}, 1 / 1.5, Vec2{X: 0.4714045, Y: -0.8819171}, true},
+		// sin(45°) * 1.5 > 1, so the ray cannot leave the denser medium
+		{"total internal reflection", diagonal, Vec2{Y: 1}, 1.5, Vec2{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.v.Refract(tt.n, tt.eta)
+		if ok != tt.wantOK || !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%s: Refract = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestVec2Distance(t *testing.T) {
+	if got := (Vec2{X: 1, Y: 1}).Distance(Vec2{X: 4, Y: 5}); got != 5 {
+		t.Errorf("Distance = %v, want 5", got)
+	}
+}
@@ -1,5 +1,7 @@
 package geometry
 
+import "math"
+
 // Vec3 represents a point or vector in three-dimensional space.
 type Vec3 struct {
 	X, Y, Z float32
@@ -50,3 +52,83 @@ func (v Vec3) Cross(u Vec3) Vec3 {
 func (v Vec3) InterpolateTo(u Vec3, alpha float32) Vec3 {
 	return u.Sub(v).Scale(alpha).Add(v)
 }
+
+// Length returns the Euclidean length of the vector.
+func (v Vec3) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns the unit vector in the direction of v. The zero vector is
+// returned unchanged.
+func (v Vec3) Normalize() Vec3 {
+	length := v.Length()
+	if length == 0 {
+		return v
+	}
+	return v.Scale(1 / length)
+}
+
+// LengthSquared returns the square of the vector's length, which is cheaper to
+// compute than Length.
+func (v Vec3) LengthSquared() float32 {
+	return v.Dot(v)
+}
+
+// Distance returns the distance between the points v and u.
+func (v Vec3) Distance(u Vec3) float32 {
+	return u.Sub(v).Length()
+}
+
+// Negate returns vector -v.
+func (v Vec3) Negate() Vec3 {
+	return Vec3{X: -v.X, Y: -v.Y, Z: -v.Z}
+}
+
+// Mul returns the component-wise product of the vector with u.
+func (v Vec3) Mul(u Vec3) Vec3 {
+	return Vec3{X: v.X * u.X, Y: v.Y * u.Y, Z: v.Z * u.Z}
+}
+
+// Min returns the component-wise minimum of the vector and u.
+func (v Vec3) Min(u Vec3) Vec3 {
+	return Vec3{X: min32(v.X, u.X), Y: min32(v.Y, u.Y), Z: min32(v.Z, u.Z)}
+}
+
+// Max returns the component-wise maximum of the vector and u.
+func (v Vec3) Max(u Vec3) Vec3 {
+	return Vec3{X: max32(v.X, u.X), Y: max32(v.Y, u.Y), Z: max32(v.Z, u.Z)}
+}
+
+// Abs returns the vector with the absolute value of each component.
+func (v Vec3) Abs() Vec3 {
+	return Vec3{X: abs32(v.X), Y: abs32(v.Y), Z: abs32(v.Z)}
+}
+
+// Clamp returns the vector with each component clamped between the
+// corresponding components of lo and hi.
+func (v Vec3) Clamp(lo, hi Vec3) Vec3 {
+	return v.Max(lo).Min(hi)
+}
+
+// Reflect returns the vector reflected off a surface with unit normal n.
+func (v Vec3) Reflect(n Vec3) Vec3 {
+	return v.Sub(n.Scale(2 * v.Dot(n)))
+}
+
+// Refract returns the unit vector v refracted through a surface with unit
+// normal n, where eta is the ratio of the refractive indices on either side of
+// the surface. It returns false if there is total internal reflection.
+func (v Vec3) Refract(n Vec3, eta float32) (Vec3, bool) {
+	cosI := -v.Dot(n)
+	k := 1 - eta*eta*(1-cosI*cosI)
+	if k < 0 {
+		return Vec3{}, false
+	}
+	return v.Scale(eta).Add(n.Scale(eta*cosI - float32(math.Sqrt(float64(k))))), true
+}
+
+// ApproxEqual returns whether each component of the vector is within epsilon
+// of the corresponding component of u.
+func (v Vec3) ApproxEqual(u Vec3, epsilon float32) bool {
+	return abs32(v.X-u.X) <= epsilon && abs32(v.Y-u.Y) <= epsilon && abs32(v.Z-u.Z) <= epsilon
+}
@@ -0,0 +1,100 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-5
+
+func TestVec3Normalize(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Vec3
+		want Vec3
+	}{
+		{"zero", Vec3{}, Vec3{}},
+		{"axis", Vec3{Y: -3}, Vec3{Y: -1}},
+		{"diagonal", Vec3{X: 1, Y: 2, Z: 2}, Vec3{X: 1.0 / 3, Y: 2.0 / 3, Z: 2.0 / 3}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Normalize(); !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%s: %v.Normalize() = %v, want %v", tt.name, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestVec3Cross(t *testing.T) {
+	tests := []struct {
+		v, u, want Vec3
+	}{
+		{Vec3{X: 1}, Vec3{Y: 1}, Vec3{Z: 1}},
+		{Vec3{Y: 1}, Vec3{Z: 1}, Vec3{X: 1}},
+		{Vec3{Z: 1}, Vec3{X: 1}, Vec3{Y: 1}},
+		{Vec3{Y: 1}, Vec3{X: 1}, Vec3{Z: -1}},
+		{Vec3{X: 2, Y: 3}, Vec3{X: 4, Y: 6}, Vec3{}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Cross(tt.u); got != tt.want {
+			t.Errorf("%v.Cross(%v) = %v, want %v", tt.v, tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestVec3Reflect(t *testing.T) {
+	tests := []struct {
+		v, n, want Vec3
+	}{
+		{Vec3{X: 1, Y: -1}, Vec3{Y: 1}, Vec3{X: 1, Y: 1}},
+		{Vec3{Z: 1}, Vec3{Z: -1}, Vec3{Z: -1}},
+		{Vec3{X: 1}, Vec3{Y: 1}, Vec3{X: 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Reflect(tt.n); !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%v.Reflect(%v) = %v, want %v", tt.v, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestVec3Refract(t *testing.T) {
+	diagonal := Vec3{X: 1, Y: -1}.Normalize()
+	tests := []struct {
+		name   string
+		v, n   Vec3
+		eta    float32
+		want   Vec3
+		wantOK bool
+	}{
+		{"head on", Vec3{Y: -1}, Vec3{Y: 1}, 1 / 1.5, Vec3{Y: -1}, true},
+		{"same medium", diagonal, Vec3{Y: 1}, 1, diagonal, true},
+		// sin(45°) * 1.5 > 1, so the ray cannot leave the denser medium
+		{"total internal reflection", diagonal, Vec3{Y: 1}, 1.5, Vec3{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.v.Refract(tt.n, tt.eta)
+		if ok != tt.wantOK || !got.ApproxEqual(tt.want, epsilon) {
+			t.Errorf("%s: Refract = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// Snell's law: eta * sin(incident) = sin(refracted)
+	got, _ := diagonal.Refract(Vec3{Y: 1}, 1/1.5)
+	if sin := float64(got.X); math.Abs(sin-math.Sqrt(0.5)/1.5) > epsilon {
+		t.Errorf("refracted sine = %v, want %v", sin, math.Sqrt(0.5)/1.5)
+	}
+}
+
+func TestVec3Clamp(t *testing.T) {
+	lo, hi := Vec3{}, Vec3{X: 1, Y: 1, Z: 1}
+	tests := []struct {
+		v, want Vec3
+	}{
+		{Vec3{X: -1, Y: 0.5, Z: 2}, Vec3{X: 0, Y: 0.5, Z: 1}},
+		{Vec3{X: 0.25, Y: 0.5, Z: 0.75}, Vec3{X: 0.25, Y: 0.5, Z: 0.75}},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Clamp(lo, hi); got != tt.want {
+			t.Errorf("%v.Clamp = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	// glTF images are PNG or JPEG
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+// isGLB returns whether data is a binary GLB file.
+func isGLB(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic
+}
+
+// splitGLB returns the JSON and binary chunks of a GLB file. The binary chunk
+// is nil if there is none.
+func splitGLB(data []byte) ([]byte, []byte, error) {
+	if len(data) < 12 {
+		return nil, nil, fmt.Errorf("GLB header is truncated")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("unsupported GLB version %d", version)
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, fmt.Errorf("GLB is truncated: expected %d bytes, found %d", length, len(data))
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= length; {
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if start+chunkLength > length {
+			return nil, nil, fmt.Errorf("GLB chunk at byte %d is truncated", offset)
+		}
+		chunk := data[start : start+chunkLength]
+		switch {
+		case chunkType == glbChunkJSON && jsonChunk == nil:
+			jsonChunk = chunk
+		case chunkType == glbChunkBIN && binChunk == nil:
+			binChunk = chunk
+		}
+		// Chunks are padded to 4 bytes
+		offset = start + (chunkLength+3)&^3
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("GLB has no JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+// loadBuffers returns the contents of every buffer.
+func (r *reader) loadBuffers() ([][]byte, error) {
+	buffers := make([][]byte, len(r.doc.Buffers))
+	for i, b := range r.doc.Buffers {
+		var data []byte
+		var err error
+		if b.URI == "" {
+			// The first buffer of a GLB file is its binary chunk
+			if i != 0 || r.bin == nil {
+				return nil, fmt.Errorf("buffer %d has no data", i)
+			}
+			data = r.bin
+		} else {
+			data, err = r.readURI(b.URI)
+			if err != nil {
+				return nil, fmt.Errorf("buffer %d: %v", i, err)
+			}
+		}
+		if len(data) < b.ByteLength {
+			return nil, fmt.Errorf("buffer %d: expected %d bytes, found %d", i, b.ByteLength, len(data))
+		}
+		buffers[i] = data
+	}
+	return buffers, nil
+}
+
+// readURI returns the data of a data URI, or the contents of a file relative to
+// the model. Other URIs are not fetched.
+func (r *reader) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("only base64 data URIs are supported")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	if strings.Contains(uri, "://") {
+		return nil, fmt.Errorf("cannot read %q: only local files are supported", uri)
+	}
+
+	// URIs of files are relative paths, which may be percent-encoded
+	path, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(r.dir, filepath.FromSlash(path)))
+}
+
+// image decodes an image from a file, a data URI or a buffer view.
+func (r *reader) image(def *imageDef) (image.Image, error) {
+	var data []byte
+	var err error
+	switch {
+	case def.BufferView != nil:
+		data, err = r.bufferViewData(*def.BufferView)
+	case def.URI != "":
+		data, err = r.readURI(def.URI)
+	default:
+		err = fmt.Errorf("image has no data")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// bufferViewData returns the bytes of the buffer view with the given index.
+func (r *reader) bufferViewData(index int) ([]byte, error) {
+	if index < 0 || index >= len(r.doc.BufferViews) {
+		return nil, fmt.Errorf("buffer view %d does not exist", index)
+	}
+	view := r.doc.BufferViews[index]
+	if view.Buffer < 0 || view.Buffer >= len(r.buffers) {
+		return nil, fmt.Errorf("buffer view %d: buffer %d does not exist", index, view.Buffer)
+	}
+	data := r.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteOffset+view.ByteLength > len(data) {
+		return nil, fmt.Errorf("buffer view %d is outside its buffer", index)
+	}
+	return data[view.ByteOffset : view.ByteOffset+view.ByteLength], nil
+}
+
+// The number of components of each accessor type.
+var accessorComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+}
+
+// The size in bytes of each accessor component type.
+var componentSizes = map[int]int{
+	5120: 1, // BYTE
+	5121: 1, // UNSIGNED_BYTE
+	5122: 2, // SHORT
+	5123: 2, // UNSIGNED_SHORT
+	5125: 4, // UNSIGNED_INT
+	5126: 4, // FLOAT
+}
+
+// readAccessor returns the elements of the accessor with the given index, each
+// with its components converted to floats. Normalized integers are scaled to
+// between 0 and 1, or -1 and 1.
+func (r *reader) readAccessor(index int) ([][]float32, error) {
+	acc, data, stride, err := r.accessorData(index)
+	if err != nil {
+		return nil, err
+	}
+	components := accessorComponents[acc.Type]
+	size := componentSizes[acc.ComponentType]
+
+	elements := make([][]float32, acc.Count)
+	for i := range elements {
+		elements[i] = make([]float32, components)
+		// Accessors without a buffer view are all zeros
+		if data == nil {
+			continue
+		}
+		offset := acc.ByteOffset + i*stride
+		for c := 0; c < components; c++ {
+			elements[i][c] = readComponent(data[offset+c*size:], acc.ComponentType, acc.Normalized)
+		}
+	}
+	return elements, nil
+}
+
+// readIndices returns the elements of a scalar accessor of unsigned integers.
+// They are read as integers, since not all 32-bit indices fit in a float.
+func (r *reader) readIndices(index int) ([]int, error) {
+	acc, data, stride, err := r.accessorData(index)
+	if err != nil {
+		return nil, err
+	}
+	if acc.Type != "SCALAR" {
+		return nil, fmt.Errorf("accessor %d: indices must be scalars, not %s", index, acc.Type)
+	}
+	if acc.ComponentType != 5121 && acc.ComponentType != 5123 && acc.ComponentType != 5125 {
+		return nil, fmt.Errorf("accessor %d: indices must be unsigned integers", index)
+	}
+
+	indices := make([]int, acc.Count)
+	if data == nil {
+		return indices, nil
+	}
+	for i := range indices {
+		b := data[acc.ByteOffset+i*stride:]
+		switch acc.ComponentType {
+		case 5121:
+			indices[i] = int(b[0])
+		case 5123:
+			indices[i] = int(binary.LittleEndian.Uint16(b))
+		case 5125:
+			indices[i] = int(binary.LittleEndian.Uint32(b))
+		}
+	}
+	return indices, nil
+}
+
+// accessorData checks the layout of the accessor with the given index, and
+// returns it along with the data of its buffer view and the distance in bytes
+// between its elements. The data is nil if the accessor has no buffer view.
+func (r *reader) accessorData(index int) (*accessor, []byte, int, error) {
+	if index < 0 || index >= len(r.doc.Accessors) {
+		return nil, nil, 0, fmt.Errorf("accessor %d does not exist", index)
+	}
+	acc := &r.doc.Accessors[index]
+	components, ok := accessorComponents[acc.Type]
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("accessor %d: unsupported type %q", index, acc.Type)
+	}
+	size, ok := componentSizes[acc.ComponentType]
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("accessor %d: unknown component type %d", index, acc.ComponentType)
+	}
+	if acc.Sparse != nil {
+		return nil, nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	if acc.Count < 0 {
+		return nil, nil, 0, fmt.Errorf("accessor %d: negative count %d", index, acc.Count)
+	}
+	if acc.BufferView == nil {
+		return acc, nil, 0, nil
+	}
+
+	data, err := r.bufferViewData(*acc.BufferView)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("accessor %d: %v", index, err)
+	}
+	stride := r.doc.BufferViews[*acc.BufferView].ByteStride
+	if stride == 0 {
+		stride = components * size
+	}
+	if acc.Count > 0 && acc.ByteOffset+(acc.Count-1)*stride+components*size > len(data) {
+		return nil, nil, 0, fmt.Errorf("accessor %d is outside its buffer view", index)
+	}
+	return acc, data, stride, nil
+}
+
+// readComponent reads a single little-endian component.
+func readComponent(data []byte, componentType int, normalized bool) float32 {
+	var value, max float32
+	switch componentType {
+	case 5120:
+		value, max = float32(int8(data[0])), math.MaxInt8
+	case 5121:
+		value, max = float32(data[0]), math.MaxUint8
+	case 5122:
+		value, max = float32(int16(binary.LittleEndian.Uint16(data))), math.MaxInt16
+	case 5123:
+		value, max = float32(binary.LittleEndian.Uint16(data)), math.MaxUint16
+	case 5125:
+		value, max = float32(binary.LittleEndian.Uint32(data)), math.MaxUint32
+	case 5126:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data))
+	}
+	if normalized {
+		// Signed values are clamped so that the most negative value is -1
+		return float32(math.Max(float64(value/max), -1))
+	}
+	return value
+}
+
+// primitive converts a primitive to a triangle list, mirroring it along the
+// Z-axis. Triangle strips and fans are converted to lists.
+func (r *reader) primitive(def *primitiveDef) (*Primitive, error) {
+	posIndex, ok := def.Attributes["POSITION"]
+	if !ok {
+		return nil, fmt.Errorf("primitive has no positions")
+	}
+	positions, err := r.readAccessor(posIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &canvas.IndexedTriangleList{Vertices: make([]geom.Vec3, len(positions))}
+	for i, p := range positions {
+		list.Vertices[i] = mirrorVec3(p[0], p[1], p[2])
+	}
+
+	if index, ok := def.Attributes["NORMAL"]; ok {
+		normals, err := r.readAccessor(index)
+		if err != nil {
+			return nil, err
+		}
+		if len(normals) != len(positions) {
+			return nil, fmt.Errorf("expected %d normals, found %d", len(positions), len(normals))
+		}
+		list.Normals = make([]geom.Vec3, len(normals))
+		for i, n := range normals {
+			list.Normals[i] = mirrorVec3(n[0], n[1], n[2])
+		}
+	}
+
+	if index, ok := def.Attributes["TEXCOORD_0"]; ok {
+		texCoords, err := r.readAccessor(index)
+		if err != nil {
+			return nil, err
+		}
+		if len(texCoords) != len(positions) {
+			return nil, fmt.Errorf("expected %d texture coordinates, found %d", len(positions), len(texCoords))
+		}
+		// glTF texture coordinates already start at the top of the image
+		list.TexCoords = make([]geom.Vec2, len(texCoords))
+		for i, t := range texCoords {
+			list.TexCoords[i] = geom.Vec2{X: t[0], Y: t[1]}
+		}
+	}
+
+	indices := make([]int, 0)
+	if def.Indices != nil {
+		var err error
+		indices, err = r.readIndices(*def.Indices)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range indices {
+			if index < 0 || index >= len(positions) {
+				return nil, fmt.Errorf("index %d out of range, only %d vertices", index, len(positions))
+			}
+		}
+	} else {
+		for i := range positions {
+			indices = append(indices, i)
+		}
+	}
+
+	mode := 4
+	if def.Mode != nil {
+		mode = *def.Mode
+	}
+	if err := setTopology(list, indices, mode); err != nil {
+		return nil, err
+	}
+
+	prim := &Primitive{Triangles: list}
+	if def.Material != nil {
+		if *def.Material < 0 || *def.Material >= len(r.model.Materials) {
+			return nil, fmt.Errorf("material %d does not exist", *def.Material)
+		}
+		prim.Material = r.model.Materials[*def.Material]
+		list.Material = *def.Material
+		list.DoubleSided = prim.Material.DoubleSided
+	}
+	return prim, nil
+}
+
+// setTopology sets the indices and topology of the list from a glTF primitive
+// mode. Mirroring reverses the winding of triangles, which we undo.
+func setTopology(list *canvas.IndexedTriangleList, indices []int, mode int) error {
+	switch mode {
+	case 0: // POINTS
+		list.Topology = canvas.PointList
+		list.Indices = indices
+	case 1: // LINES
+		list.Topology = canvas.LineList
+		list.Indices = indices
+	case 2: // LINE_LOOP
+		list.Topology = canvas.LineStrip
+		list.Indices = indices
+		if len(indices) > 0 {
+			list.Indices = append(indices, indices[0])
+		}
+	case 3: // LINE_STRIP
+		list.Topology = canvas.LineStrip
+		list.Indices = indices
+	case 4: // TRIANGLES
+		list.Topology = canvas.TriangleList
+		for i := 0; i+2 < len(indices); i += 3 {
+			list.Indices = append(list.Indices, indices[i], indices[i+2], indices[i+1])
+		}
+	case 5: // TRIANGLE_STRIP
+		list.Topology = canvas.TriangleList
+		for i := 0; i+2 < len(indices); i++ {
+			// Every other triangle in a strip has the opposite winding
+			if i%2 == 0 {
+				list.Indices = append(list.Indices, indices[i], indices[i+2], indices[i+1])
+			} else {
+				list.Indices = append(list.Indices, indices[i], indices[i+1], indices[i+2])
+			}
+		}
+	case 6: // TRIANGLE_FAN
+		list.Topology = canvas.TriangleList
+		for i := 1; i+1 < len(indices); i++ {
+			list.Indices = append(list.Indices, indices[0], indices[i+1], indices[i])
+		}
+	default:
+		return fmt.Errorf("unknown primitive mode %d", mode)
+	}
+	return nil
+}
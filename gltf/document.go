@@ -0,0 +1,106 @@
+package gltf
+
+// The structure of the JSON part of a glTF file, limited to the properties we
+// read.
+
+type document struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	ExtensionsRequired []string      `json:"extensionsRequired"`
+	Scene              *int          `json:"scene"`
+	Scenes             []sceneDef    `json:"scenes"`
+	Nodes              []node        `json:"nodes"`
+	Meshes             []meshDef     `json:"meshes"`
+	Materials          []materialDef `json:"materials"`
+	Textures           []textureDef  `json:"textures"`
+	Images             []imageDef    `json:"images"`
+	Cameras            []cameraDef   `json:"cameras"`
+	Accessors          []accessor    `json:"accessors"`
+	BufferViews        []bufferView  `json:"bufferViews"`
+	Buffers            []buffer      `json:"buffers"`
+}
+
+type sceneDef struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type node struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Matrix      []float32 `json:"matrix"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+}
+
+type meshDef struct {
+	Name       string         `json:"name"`
+	Primitives []primitiveDef `json:"primitives"`
+}
+
+type primitiveDef struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type materialDef struct {
+	Name                 string `json:"name"`
+	DoubleSided          bool   `json:"doubleSided"`
+	PBRMetallicRoughness *struct {
+		BaseColorFactor  []float32 `json:"baseColorFactor"`
+		BaseColorTexture *struct {
+			Index int `json:"index"`
+		} `json:"baseColorTexture"`
+	} `json:"pbrMetallicRoughness"`
+}
+
+type textureDef struct {
+	Source *int `json:"source"`
+}
+
+type imageDef struct {
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type cameraDef struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		AspectRatio *float32 `json:"aspectRatio"`
+		YFov        float32  `json:"yfov"`
+	} `json:"perspective"`
+	Orthographic *struct {
+		XMag float32 `json:"xmag"`
+		YMag float32 `json:"ymag"`
+	} `json:"orthographic"`
+}
+
+type accessor struct {
+	BufferView    *int      `json:"bufferView"`
+	ByteOffset    int       `json:"byteOffset"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Sparse        *struct{} `json:"sparse"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type buffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
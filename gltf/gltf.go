@@ -0,0 +1,419 @@
+// Package gltf reads shapes, materials, cameras and their arrangement from glTF
+// 2.0 files, either as JSON with separate or embedded buffers, or as binary GLB
+// files. Only local files and data URIs are read.
+//
+// glTF uses a right-handed coordinate system, so everything is mirrored along
+// the Z-axis to fit the left-handed system used by the rasterizer.
+package gltf
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+	"rasterizer/scene"
+)
+
+// Model is the contents of a glTF file.
+type Model struct {
+	// The root node of each scene in the file. Scenes cannot share nodes.
+	Scenes []*scene.Node
+	// The scene to show by default, or nil if the file does not say.
+	Scene     *scene.Node
+	Meshes    []*Mesh
+	Materials []*Material
+	Cameras   []*Camera
+}
+
+// Mesh is a shape made of one or more primitives, which can each have a
+// different material.
+type Mesh struct {
+	Name       string
+	Primitives []*Primitive
+}
+
+// Primitive is a part of a mesh with a single material.
+type Primitive struct {
+	// The Material of the triangles is the index of the primitive's material in
+	// the model's Materials.
+	Triangles *canvas.IndexedTriangleList
+	// The material of the primitive, or nil if it has none.
+	Material *Material
+}
+
+// Material describes the surface of a primitive.
+type Material struct {
+	Name string
+	// The linear RGB base color, which multiplies the colors of the texture.
+	BaseColor geom.Vec3
+	Opacity   float32
+	// The base color texture, or nil if there is none.
+	Image image.Image
+	// Whether the back faces of primitives with the material are drawn. This
+	// also sets DoubleSided on their triangle lists.
+	DoubleSided bool
+	// Shades surfaces with the base color and texture.
+	Texture canvas.Texture
+}
+
+// Camera is a viewpoint defined in the file.
+type Camera struct {
+	Name       string
+	Projection geom.Projection
+	// The nodes the camera is attached to. The camera looks along the positive
+	// Z-axis of each node.
+	Nodes []*scene.Node
+}
+
+// Load reads the glTF or GLB file at path. Buffers and images referred to by
+// the file are read relative to it.
+func Load(path string) (*Model, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	model, err := Read(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return model, nil
+}
+
+// Read reads a model from the contents of a glTF or GLB file. Buffers and
+// images referred to by the file are read relative to dir.
+func Read(data []byte, dir string) (*Model, error) {
+	jsonChunk, binChunk := data, []byte(nil)
+	if isGLB(data) {
+		var err error
+		jsonChunk, binChunk, err = splitGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var doc document
+	if err := json.Unmarshal(jsonChunk, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if doc.Asset.Version != "" && doc.Asset.Version[0] != '2' {
+		return nil, fmt.Errorf("unsupported glTF version %s", doc.Asset.Version)
+	}
+	// No extensions are supported, so files that cannot be read without one
+	// are rejected rather than drawn wrongly
+	if len(doc.ExtensionsRequired) > 0 {
+		return nil, fmt.Errorf("unsupported required extension %q", doc.ExtensionsRequired[0])
+	}
+
+	r := &reader{doc: &doc, dir: dir, bin: binChunk}
+	return r.read()
+}
+
+// reader converts a parsed glTF document into a Model.
+type reader struct {
+	doc *document
+	dir string
+	// The binary chunk of a GLB file
+	bin     []byte
+	buffers [][]byte
+	model   *Model
+}
+
+func (r *reader) read() (*Model, error) {
+	r.model = &Model{}
+
+	buffers, err := r.loadBuffers()
+	if err != nil {
+		return nil, err
+	}
+	r.buffers = buffers
+
+	for i := range r.doc.Materials {
+		mat, err := r.material(i)
+		if err != nil {
+			return nil, fmt.Errorf("material %d: %v", i, err)
+		}
+		r.model.Materials = append(r.model.Materials, mat)
+	}
+
+	for i, m := range r.doc.Meshes {
+		mesh := &Mesh{Name: m.Name}
+		for j := range m.Primitives {
+			prim, err := r.primitive(&m.Primitives[j])
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d, primitive %d: %v", i, j, err)
+			}
+			mesh.Primitives = append(mesh.Primitives, prim)
+		}
+		r.model.Meshes = append(r.model.Meshes, mesh)
+	}
+
+	for i, c := range r.doc.Cameras {
+		cam, err := camera(&c)
+		if err != nil {
+			return nil, fmt.Errorf("camera %d: %v", i, err)
+		}
+		r.model.Cameras = append(r.model.Cameras, cam)
+	}
+
+	nodes, err := r.nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, s := range r.doc.Scenes {
+		root := scene.NewNode(s.Name)
+		for _, n := range s.Nodes {
+			if n < 0 || n >= len(nodes) {
+				return nil, fmt.Errorf("scene %d: node %d does not exist", i, n)
+			}
+			// A node can only have one parent, so it cannot be shared between
+			// scenes or also be the child of another node
+			if nodes[n].Parent() != nil {
+				return nil, fmt.Errorf("scene %d: node %d is already part of another scene or node", i, n)
+			}
+			root.AddChild(nodes[n])
+		}
+		r.model.Scenes = append(r.model.Scenes, root)
+	}
+	if r.doc.Scene != nil {
+		if *r.doc.Scene < 0 || *r.doc.Scene >= len(r.model.Scenes) {
+			return nil, fmt.Errorf("scene %d does not exist", *r.doc.Scene)
+		}
+		r.model.Scene = r.model.Scenes[*r.doc.Scene]
+	}
+	return r.model, nil
+}
+
+// nodes builds the node hierarchy, returning a scene node for every node in
+// the file.
+func (r *reader) nodes() ([]*scene.Node, error) {
+	nodes := make([]*scene.Node, len(r.doc.Nodes))
+	for i, n := range r.doc.Nodes {
+		node := scene.NewNode(n.Name)
+		transform, err := nodeTransform(&n)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %v", i, err)
+		}
+		node.Transform = transform
+
+		if n.Mesh != nil {
+			if *n.Mesh < 0 || *n.Mesh >= len(r.model.Meshes) {
+				return nil, fmt.Errorf("node %d: mesh %d does not exist", i, *n.Mesh)
+			}
+			attachMesh(node, r.model.Meshes[*n.Mesh])
+		}
+		if n.Camera != nil {
+			if *n.Camera < 0 || *n.Camera >= len(r.model.Cameras) {
+				return nil, fmt.Errorf("node %d: camera %d does not exist", i, *n.Camera)
+			}
+			cam := r.model.Cameras[*n.Camera]
+			cam.Nodes = append(cam.Nodes, node)
+		}
+		nodes[i] = node
+	}
+
+	for i, n := range r.doc.Nodes {
+		for _, child := range n.Children {
+			if child < 0 || child >= len(nodes) {
+				return nil, fmt.Errorf("node %d: child %d does not exist", i, child)
+			}
+			if nodes[child].Parent() != nil {
+				return nil, fmt.Errorf("node %d: child %d already has a parent", i, child)
+			}
+			for p := nodes[i]; p != nil; p = p.Parent() {
+				if p == nodes[child] {
+					return nil, fmt.Errorf("node %d: child %d would create a cycle", i, child)
+				}
+			}
+			nodes[i].AddChild(nodes[child])
+		}
+	}
+	return nodes, nil
+}
+
+// attachMesh draws the mesh at the node. A node can only have one shape, so
+// meshes with several primitives are attached to child nodes instead.
+func attachMesh(node *scene.Node, mesh *Mesh) {
+	if len(mesh.Primitives) == 1 {
+		setPrimitive(node, mesh.Primitives[0])
+		return
+	}
+	for i, prim := range mesh.Primitives {
+		child := scene.NewNode(fmt.Sprintf("%s primitive %d", mesh.Name, i))
+		setPrimitive(child, prim)
+		node.AddChild(child)
+	}
+}
+
+func setPrimitive(node *scene.Node, prim *Primitive) {
+	node.Mesh = prim.Triangles
+	if prim.Material != nil {
+		node.Texture = prim.Material.Texture
+	} else {
+		node.Texture = &canvas.Tinted{Factor: geom.Vec3{X: 1, Y: 1, Z: 1}}
+	}
+}
+
+// nodeTransform returns the node's transform, mirrored along the Z-axis.
+func nodeTransform(n *node) (scene.Transform, error) {
+	t := scene.NewTransform()
+	lengths := []struct {
+		name   string
+		values []float32
+		length int
+	}{
+		{"matrix", n.Matrix, 16},
+		{"translation", n.Translation, 3},
+		{"rotation", n.Rotation, 4},
+		{"scale", n.Scale, 3},
+	}
+	for _, l := range lengths {
+		if l.values != nil && len(l.values) != l.length {
+			return t, fmt.Errorf("node %s has %d values, expected %d", l.name, len(l.values), l.length)
+		}
+	}
+
+	if n.Matrix != nil {
+		// Matrices are stored column by column
+		var m geom.Mat4
+		for col := 0; col < 4; col++ {
+			for row := 0; row < 4; row++ {
+				m[row][col] = n.Matrix[col*4+row]
+			}
+		}
+		if !m.Affine() {
+			return t, fmt.Errorf("node matrix is not affine")
+		}
+		return decompose(mirrorMatrix(&m)), nil
+	}
+
+	if n.Translation != nil {
+		t.Translation = mirrorVec3(n.Translation[0], n.Translation[1], n.Translation[2])
+	}
+	if n.Rotation != nil {
+		t.Rotation = mirrorQuat(geom.Quat{X: n.Rotation[0], Y: n.Rotation[1], Z: n.Rotation[2], W: n.Rotation[3]})
+	}
+	if n.Scale != nil {
+		t.Scale = geom.Vec3{X: n.Scale[0], Y: n.Scale[1], Z: n.Scale[2]}
+	}
+	return t, nil
+}
+
+// decompose splits an affine matrix without shear into translation, rotation
+// and scale.
+func decompose(m *geom.Mat4) scene.Transform {
+	t := scene.NewTransform()
+	t.Translation = m.Translation()
+
+	linear := m.Mat3()
+	axes := linear.Transpose()
+	t.Scale = geom.Vec3{
+		X: geom.Vec3{X: axes[0][0], Y: axes[0][1], Z: axes[0][2]}.Length(),
+		Y: geom.Vec3{X: axes[1][0], Y: axes[1][1], Z: axes[1][2]}.Length(),
+		Z: geom.Vec3{X: axes[2][0], Y: axes[2][1], Z: axes[2][2]}.Length(),
+	}
+	// A reflection is represented by a negative scale
+	if linear.Determinant() < 0 {
+		t.Scale.X = -t.Scale.X
+	}
+	if t.Scale.X == 0 || t.Scale.Y == 0 || t.Scale.Z == 0 {
+		return t
+	}
+
+	rotation := linear.MatMul(geom.Scaling(geom.Vec3{X: 1 / t.Scale.X, Y: 1 / t.Scale.Y, Z: 1 / t.Scale.Z}))
+	t.Rotation = geom.QuatFromMat3(rotation)
+	return t
+}
+
+func mirrorVec3(x, y, z float32) geom.Vec3 {
+	return geom.Vec3{X: x, Y: y, Z: -z}
+}
+
+// mirrorQuat returns the rotation q as seen in a mirror along the Z-axis.
+func mirrorQuat(q geom.Quat) geom.Quat {
+	return geom.Quat{X: -q.X, Y: -q.Y, Z: q.Z, W: q.W}
+}
+
+// mirrorMatrix returns the transform m as seen in a mirror along the Z-axis.
+func mirrorMatrix(m *geom.Mat4) *geom.Mat4 {
+	mirror := geom.Mat4FromMat3(geom.Scaling(geom.Vec3{X: 1, Y: 1, Z: -1}))
+	return mirror.MatMul(m).MatMul(mirror)
+}
+
+// material converts the material with the given index.
+func (r *reader) material(index int) (*Material, error) {
+	m := &r.doc.Materials[index]
+	pbr := m.PBRMetallicRoughness
+	mat := &Material{
+		Name:        m.Name,
+		BaseColor:   geom.Vec3{X: 1, Y: 1, Z: 1},
+		Opacity:     1,
+		DoubleSided: m.DoubleSided,
+	}
+	if pbr != nil && pbr.BaseColorFactor != nil {
+		f := pbr.BaseColorFactor
+		if len(f) != 4 {
+			return nil, fmt.Errorf("base color factor has %d values, expected 4", len(f))
+		}
+		mat.BaseColor = geom.Vec3{X: f[0], Y: f[1], Z: f[2]}
+		mat.Opacity = f[3]
+	}
+
+	var tex canvas.Texture
+	if pbr != nil && pbr.BaseColorTexture != nil {
+		img, err := r.texture(pbr.BaseColorTexture.Index)
+		if err != nil {
+			return nil, err
+		}
+		mat.Image = img
+		tex = &canvas.ImageTextureWrapped{Img: img, Scale: 1}
+	}
+	mat.Texture = &canvas.Tinted{Texture: tex, Factor: mat.BaseColor}
+	return mat, nil
+}
+
+// texture returns the image of the texture with the given index.
+func (r *reader) texture(index int) (image.Image, error) {
+	if index < 0 || index >= len(r.doc.Textures) {
+		return nil, fmt.Errorf("texture %d does not exist", index)
+	}
+	source := r.doc.Textures[index].Source
+	if source == nil || *source < 0 || *source >= len(r.doc.Images) {
+		return nil, fmt.Errorf("texture %d has no image", index)
+	}
+	img, err := r.image(&r.doc.Images[*source])
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %v", *source, err)
+	}
+	return img, nil
+}
+
+// camera converts a camera, mirroring it along the Z-axis so that it looks
+// along the positive Z-axis.
+func camera(c *cameraDef) (*Camera, error) {
+	cam := &Camera{Name: c.Name}
+	switch c.Type {
+	case "perspective":
+		if c.Perspective == nil {
+			return nil, fmt.Errorf("missing perspective properties")
+		}
+		aspect := float32(1)
+		if c.Perspective.AspectRatio != nil {
+			aspect = *c.Perspective.AspectRatio
+		}
+		cam.Projection = geom.Perspective{FOV: c.Perspective.YFov, Aspect: aspect}
+	case "orthographic":
+		if c.Orthographic == nil {
+			return nil, fmt.Errorf("missing orthographic properties")
+		}
+		o := c.Orthographic
+		cam.Projection = geom.Orthographic{Left: -o.XMag, Right: o.XMag, Bottom: -o.YMag, Top: o.YMag}
+	default:
+		return nil, fmt.Errorf("unknown camera type %q", c.Type)
+	}
+	return cam, nil
+}
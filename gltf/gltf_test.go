@@ -0,0 +1,280 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+const epsilon = 1e-5
+
+// littleEndian returns the values packed as little-endian binary.
+func littleEndian(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func dataURI(data []byte) string {
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// triangleData holds the positions of a triangle, followed by its indices as
+// unsigned shorts, padded to 4 bytes.
+var triangleData = littleEndian(
+	[]float32{0, 0, 1, 1, 0, 1, 0, 1, 2},
+	[]uint16{0, 1, 2, 0},
+)
+
+// triangleJSON is a scene with a single triangle. The buffer's URI is left out
+// when it is the binary chunk of a GLB file.
+func triangleJSON(uri string) string {
+	buffer := `{"byteLength":44}`
+	if uri != "" {
+		buffer = `{"byteLength":44,"uri":"` + uri + `"}`
+	}
+	return `{
+		"asset":{"version":"2.0"},
+		"scene":0,
+		"scenes":[{"nodes":[0]}],
+		"nodes":[{"mesh":0,"translation":[1,2,3]}],
+		"meshes":[{"primitives":[{"attributes":{"POSITION":0},"indices":1}]}],
+		"accessors":[
+			{"bufferView":0,"componentType":5126,"count":3,"type":"VEC3"},
+			{"bufferView":1,"componentType":5123,"count":3,"type":"SCALAR"}
+		],
+		"bufferViews":[
+			{"buffer":0,"byteLength":36},
+			{"buffer":0,"byteOffset":36,"byteLength":6}
+		],
+		"buffers":[` + buffer + `]
+	}`
+}
+
+// glb packs chunks into a GLB file, each given as its type followed by its
+// data.
+func glb(chunks ...interface{}) []byte {
+	var body []byte
+	for i := 0; i < len(chunks); i += 2 {
+		data := chunks[i+1].([]byte)
+		for len(data)%4 != 0 {
+			data = append(data, ' ')
+		}
+		body = append(body, littleEndian(uint32(len(data)), chunks[i].(uint32))...)
+		body = append(body, data...)
+	}
+	return append(littleEndian(uint32(glbMagic), uint32(2), uint32(12+len(body))), body...)
+}
+
+func TestReadTriangle(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"data URI", []byte(triangleJSON(dataURI(triangleData)))},
+		{"GLB", glb(uint32(glbChunkJSON), []byte(triangleJSON("")), uint32(glbChunkBIN), triangleData)},
+	}
+	for _, tt := range tests {
+		model, err := Read(tt.data, ".")
+		if err != nil {
+			t.Errorf("%s: Read() error = %v", tt.name, err)
+			continue
+		}
+		if model.Scene == nil || len(model.Scene.Children()) != 1 {
+			t.Errorf("%s: default scene = %v, want one node", tt.name, model.Scene)
+			continue
+		}
+		node := model.Scene.Children()[0]
+		if want := (geom.Vec3{X: 1, Y: 2, Z: -3}); node.Transform.Translation != want {
+			t.Errorf("%s: translation = %v, want %v", tt.name, node.Transform.Translation, want)
+		}
+		list := node.Mesh
+		if want := []geom.Vec3{{Z: -1}, {X: 1, Z: -1}, {Y: 1, Z: -2}}; !reflect.DeepEqual(list.Vertices, want) {
+			t.Errorf("%s: vertices = %v, want %v", tt.name, list.Vertices, want)
+		}
+		// Mirroring reverses the winding, which is undone
+		if want := []int{0, 2, 1}; !reflect.DeepEqual(list.Indices, want) {
+			t.Errorf("%s: indices = %v, want %v", tt.name, list.Indices, want)
+		}
+	}
+}
+
+func TestReadStride(t *testing.T) {
+	// Positions and normals interleaved in one buffer view
+	data := littleEndian([]float32{
+		0, 0, 0, 0, 0, 1,
+		1, 0, 0, 0, 1, 0,
+		0, 1, 0, 1, 0, 0,
+	})
+	doc := `{
+		"asset":{"version":"2.0"},
+		"meshes":[{"primitives":[{"attributes":{"POSITION":0,"NORMAL":1}}]}],
+		"accessors":[
+			{"bufferView":0,"componentType":5126,"count":3,"type":"VEC3"},
+			{"bufferView":0,"byteOffset":12,"componentType":5126,"count":3,"type":"VEC3"}
+		],
+		"bufferViews":[{"buffer":0,"byteLength":72,"byteStride":24}],
+		"buffers":[{"byteLength":72,"uri":"` + dataURI(data) + `"}]
+	}`
+	model, err := Read([]byte(doc), ".")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	list := model.Meshes[0].Primitives[0].Triangles
+	if want := []geom.Vec3{{}, {X: 1}, {Y: 1}}; !reflect.DeepEqual(list.Vertices, want) {
+		t.Errorf("vertices = %v, want %v", list.Vertices, want)
+	}
+	if want := []geom.Vec3{{Z: -1}, {Y: 1}, {X: 1}}; !reflect.DeepEqual(list.Normals, want) {
+		t.Errorf("normals = %v, want %v", list.Normals, want)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	triangle := triangleJSON(dataURI(triangleData))
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			"unsupported version",
+			`{"asset":{"version":"1.0"}}`,
+			"unsupported glTF version",
+		},
+		{
+			"required extension",
+			`{"asset":{"version":"2.0"},"extensionsRequired":["KHR_draco_mesh_compression"]}`,
+			`unsupported required extension "KHR_draco_mesh_compression"`,
+		},
+		{
+			"node shared between scenes",
+			`{"asset":{"version":"2.0"},"scenes":[{"nodes":[0]},{"nodes":[0]}],"nodes":[{}]}`,
+			"already part of another scene",
+		},
+		{
+			"scene node that is also a child",
+			`{"asset":{"version":"2.0"},"scenes":[{"nodes":[0,1]}],"nodes":[{"children":[1]},{}]}`,
+			"already part of another scene",
+		},
+		{
+			"child with two parents",
+			`{"asset":{"version":"2.0"},"nodes":[{"children":[2]},{"children":[2]},{}]}`,
+			"already has a parent",
+		},
+		{
+			"cycle",
+			`{"asset":{"version":"2.0"},"nodes":[{"children":[1]},{"children":[0]}]}`,
+			"would create a cycle",
+		},
+		{
+			"own child",
+			`{"asset":{"version":"2.0"},"nodes":[{"children":[0]}]}`,
+			"would create a cycle",
+		},
+		{
+			"short translation",
+			`{"asset":{"version":"2.0"},"nodes":[{"translation":[1,2]}]}`,
+			"translation has 2 values",
+		},
+		{
+			"accessor outside its buffer view",
+			strings.Replace(triangle, `"count":3,"type":"VEC3"`, `"count":4,"type":"VEC3"`, 1),
+			"outside its buffer view",
+		},
+		{
+			"float indices",
+			strings.Replace(triangle, `"componentType":5123,"count":3`, `"componentType":5126,"count":1`, 1),
+			"indices must be unsigned integers",
+		},
+		{
+			"text data URI",
+			strings.Replace(triangle, dataURI(triangleData), "data:text/plain,abc", 1),
+			"only base64 data URIs",
+		},
+		{
+			"truncated GLB",
+			string(glb(uint32(glbChunkJSON), []byte(triangle))[:20]),
+			"truncated",
+		},
+	}
+	for _, tt := range tests {
+		_, err := Read([]byte(tt.data), ".")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Read() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSetTopology(t *testing.T) {
+	indices := []int{0, 1, 2, 3}
+	tests := []struct {
+		name     string
+		mode     int
+		topology canvas.Topology
+		want     []int
+	}{
+		{"points", 0, canvas.PointList, []int{0, 1, 2, 3}},
+		{"line loop", 2, canvas.LineStrip, []int{0, 1, 2, 3, 0}},
+		{"triangles", 4, canvas.TriangleList, []int{0, 2, 1}},
+		// Both triangles keep the winding of the first, reversed by the mirror
+		{"triangle strip", 5, canvas.TriangleList, []int{0, 2, 1, 1, 2, 3}},
+		{"triangle fan", 6, canvas.TriangleList, []int{0, 2, 1, 0, 3, 2}},
+	}
+	for _, tt := range tests {
+		list := &canvas.IndexedTriangleList{}
+		if err := setTopology(list, append([]int(nil), indices...), tt.mode); err != nil {
+			t.Errorf("%s: setTopology() error = %v", tt.name, err)
+			continue
+		}
+		if list.Topology != tt.topology || !reflect.DeepEqual(list.Indices, tt.want) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, list.Topology, list.Indices, tt.topology, tt.want)
+		}
+	}
+	if err := setTopology(&canvas.IndexedTriangleList{}, indices, 7); err == nil {
+		t.Errorf("setTopology() accepted mode 7")
+	}
+}
+
+func TestMirrorQuat(t *testing.T) {
+	// Rotating and then mirroring is the same as mirroring and then applying
+	// the mirrored rotation
+	mirror := func(v geom.Vec3) geom.Vec3 { return geom.Vec3{X: v.X, Y: v.Y, Z: -v.Z} }
+	v := geom.Vec3{X: 0.3, Y: -0.5, Z: 0.8}
+	for _, axis := range []geom.Vec3{{X: 1}, {Y: 1}, {Z: 1}, {X: 1, Y: 2, Z: 3}} {
+		q := geom.QuatFromAxisAngle(axis.Normalize(), 0.7)
+		want := mirror(q.Rotate(v))
+		if got := mirrorQuat(q).Rotate(mirror(v)); !got.ApproxEqual(want, epsilon) {
+			t.Errorf("axis %v: got %v, want %v", axis, got, want)
+		}
+	}
+}
+
+func TestReadComponent(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		componentType int
+		normalized    bool
+		want          float32
+	}{
+		{"float", littleEndian(float32(-2.5)), 5126, false, -2.5},
+		{"byte", []byte{0xfe}, 5120, false, -2},
+		{"normalized byte", []byte{0x80}, 5120, true, -1},
+		{"normalized unsigned byte", []byte{0xff}, 5121, true, 1},
+		{"normalized unsigned short", littleEndian(uint16(math.MaxUint16)), 5123, true, 1},
+	}
+	for _, tt := range tests {
+		if got := readComponent(tt.data, tt.componentType, tt.normalized); got != tt.want {
+			t.Errorf("%s: readComponent() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -7,13 +7,17 @@ import (
 	"image/color"
 	_ "image/png"
 	"log"
+	"math"
 	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 
 	"rasterizer/canvas"
 	geom "rasterizer/geometry"
+	"rasterizer/mesh"
+	"rasterizer/postfx"
+	"rasterizer/scene"
 )
 
 const (
@@ -31,13 +35,17 @@ var colors = []color.RGBA{
 }
 
 type game struct {
-	pipeline     Pipeline
-	cubes        []canvas.IndexedTriangleList
-	tex          canvas.ImageTextureWrapped
-	vertexShader *VertexRotator
-	thetaX       float32
-	thetaY       float32
-	thetaZ       float32
+	pipeline Pipeline
+	scene    *scene.Node
+	// The node rotated by the keyboard, chosen by clicking on it.
+	selected *scene.Node
+	// Applied to the canvas after the scene has been drawn.
+	effects postfx.Chain
+	// Describes what was last clicked on.
+	picked string
+	// The projections that can be switched between, and the one in use.
+	projections []geom.Projection
+	projection  int
 }
 
 func main() {
@@ -55,24 +63,34 @@ func main() {
 		log.Fatal(err)
 	}
 
-	cubes := make([]canvas.IndexedTriangleList, 0)
-	cubes = append(cubes, *buildCube(geom.Vec3{X: -0.5, Y: 1, Z: 4}, 2.0))
-	cubes = append(cubes, *buildCube(geom.Vec3{X: 0.5, Y: 0, Z: 5}, 3.5))
-
-	vertexShader := &VertexRotator{
-		rotation:       *geom.RotationZ(0),
-		rotationCenter: cubes[0].Vertices[0].Add(cubes[0].Vertices[6]).Scale(0.5),
+	tex := &canvas.ImageTextureWrapped{Img: img, Scale: 0.25}
+	root := scene.NewNode("Scene")
+	cubes := []struct {
+		center geom.Vec3
+		length float32
+	}{
+		{center: geom.Vec3{X: -0.5, Y: 1, Z: 4}, length: 2},
+		{center: geom.Vec3{X: 0.875, Y: 0, Z: 8.75}, length: 3.5},
+	}
+	for i, cube := range cubes {
+		node := scene.NewNode(fmt.Sprintf("Cube %d", i))
+		node.Mesh = mesh.Box(geom.Vec3{X: cube.length, Y: cube.length, Z: cube.length})
+		node.Texture = tex
+		node.Transform.Translation = cube.center
+		root.AddChild(node)
 	}
 
 	g := game{
 		pipeline: Pipeline{
-			canv:           *canvas.NewCanvas(screenWidth, screenHeight),
-			vertexShader:   vertexShader,
-			geometryShader: &CubeShader{},
+			canv: *canvas.NewCanvas(screenWidth, screenHeight),
+		},
+		scene:    root,
+		selected: root.Children()[0],
+		projections: []geom.Projection{
+			geom.Perspective{FOV: math.Pi / 2, Aspect: 1},
+			geom.Orthographic{Left: -5, Right: 5, Bottom: -5, Top: 5},
+			geom.Cabinet(geom.Orthographic{Left: -5, Right: 5, Bottom: -5, Top: 5}, 6),
 		},
-		vertexShader: vertexShader,
-		tex:          canvas.ImageTextureWrapped{Img: img, Scale: 0.25},
-		cubes:        cubes,
 	}
 
 	if err := ebiten.RunGame(&g); err != nil {
@@ -90,138 +108,62 @@ func imageFromPath(path string) (image.Image, error) {
 	return image, err
 }
 
-func buildCube(center geom.Vec3, length float32) *canvas.IndexedTriangleList {
-	return &canvas.IndexedTriangleList{
-		Vertices: []geom.Vec3{
-			center.Add(geom.Vec3{X: -1, Y: 1, Z: -1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: 1, Y: 1, Z: -1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: 1, Y: -1, Z: -1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: -1, Y: -1, Z: -1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: -1, Y: 1, Z: 1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: 1, Y: 1, Z: 1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: 1, Y: -1, Z: 1}).Scale(length / 2),
-			center.Add(geom.Vec3{X: -1, Y: -1, Z: 1}).Scale(length / 2),
-		},
-		/*  Orientation of vertices
-		 *         4--------5
-		 *        /|       /|
-		 *       / |      / |
-		 *      0--------1  |
-		 *      |  7-----|--6
-		 *      | /      | /
-		 *      |/       |/
-		 *      3--------2
-		 */
-		Indices: []int{
-			// Front
-			3, 0, 1,
-			3, 1, 2,
-
-			// Back
-			6, 5, 4,
-			6, 4, 7,
-
-			// Left
-			7, 4, 0,
-			7, 0, 3,
-
-			// Right
-			2, 1, 5,
-			2, 5, 6,
-
-			// Top
-			0, 4, 5,
-			0, 5, 1,
-
-			// Bottom
-			7, 3, 2,
-			7, 2, 6,
-		},
-	}
-}
-
 func (g *game) Update() error {
+	transform := &g.selected.Transform
 	if ebiten.IsKeyPressed(ebiten.Key1) {
-		g.thetaZ += 0.05
+		transform.Rotate(geom.Vec3{Z: 1}, 0.05)
 	}
 	if ebiten.IsKeyPressed(ebiten.Key2) {
-		g.thetaZ -= 0.05
+		transform.Rotate(geom.Vec3{Z: 1}, -0.05)
 	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyQ) {
-		g.thetaX += 0.05
+		transform.Rotate(geom.Vec3{X: 1}, 0.05)
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		g.thetaX -= 0.05
+		transform.Rotate(geom.Vec3{X: 1}, -0.05)
 	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		g.thetaY += 0.05
+		transform.Rotate(geom.Vec3{Y: 1}, 0.05)
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyS) {
-		g.thetaY -= 0.05
+		transform.Rotate(geom.Vec3{Y: 1}, -0.05)
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.projection = (g.projection + 1) % len(g.projections)
+		g.pipeline.projection = g.projections[g.projection]
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x, y := ebiten.CursorPosition()
+		if node, triangle, ok := g.pipeline.Pick(x, y, g.scene); ok {
+			g.selected = node
+			g.picked = fmt.Sprintf("%s, triangle %d", node.Name, triangle)
+		} else {
+			g.picked = ""
+		}
 	}
-	g.vertexShader.rotation = *geom.RotationX(g.thetaX).
-		MatMul(geom.RotationY(g.thetaY)).
-		MatMul(geom.RotationZ(g.thetaZ))
 	return nil
 }
 
 func (g *game) Draw(screen *ebiten.Image) {
 	g.pipeline.canv.Clear()
 
-	for _, cube := range g.cubes {
-		g.pipeline.Draw(&cube, &g.tex)
-	}
+	g.pipeline.DrawScene(g.scene)
+	g.pipeline.Flush()
+	g.effects.Apply(&g.pipeline.canv)
+	g.pipeline.canv.DrawText(fmt.Sprintf("TPS: %0.2f", ebiten.CurrentTPS()), 2, 2, canvas.TextOptions{
+		Color: color.White,
+	})
+	g.pipeline.canv.DrawText(g.picked, 2, 12, canvas.TextOptions{
+		Color: color.White,
+	})
 
 	screen.ReplacePixels(g.pipeline.canv.Buffer())
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %0.2f", ebiten.CurrentTPS()))
 }
 
 func (g *game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return g.pipeline.canv.Dimensions()
 }
-
-// CubeShader tells you how to shade cubes.
-type CubeShader struct{}
-
-var topTriangle = []geom.Vec2{
-	{X: 0, Y: 1},
-	{X: 0, Y: 0},
-	{X: 1, Y: 0},
-}
-
-var bottomTriangle = []geom.Vec2{
-	{X: 0, Y: 1},
-	{X: 1, Y: 0},
-	{X: 1, Y: 1},
-}
-
-// Process returns the given vertices.
-func (s *CubeShader) Process(vertices []geom.Vec3, index int) []canvas.TexVertex {
-	processed := make([]canvas.TexVertex, 0, len(vertices))
-	for i := 0; i < len(vertices); i++ {
-		var texPos geom.Vec2
-		if index%2 == 0 {
-			texPos = topTriangle[i]
-		} else {
-			texPos = bottomTriangle[i]
-		}
-		processed = append(processed, canvas.TexVertex{
-			Pos:    vertices[i],
-			TexPos: texPos,
-		})
-	}
-	return processed
-}
-
-// VertexRotator rotates vertices.
-type VertexRotator struct {
-	rotation       geom.Mat3
-	rotationCenter geom.Vec3
-}
-
-// Process rotates the vertex.
-func (s *VertexRotator) Process(v geom.Vec3) geom.Vec3 {
-	return s.rotation.VecMul(v.Sub(s.rotationCenter)).Add(s.rotationCenter)
-}
@@ -0,0 +1,52 @@
+package mesh
+
+import (
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// boxFaces are the outward normal of each face of a box, and the directions
+// across and down the face as seen from outside it.
+var boxFaces = []struct{ normal, across, down geom.Vec3 }{
+	{normal: geom.Vec3{Z: -1}, across: geom.Vec3{X: 1}, down: geom.Vec3{Y: -1}},
+	{normal: geom.Vec3{Z: 1}, across: geom.Vec3{X: -1}, down: geom.Vec3{Y: -1}},
+	{normal: geom.Vec3{X: -1}, across: geom.Vec3{Z: -1}, down: geom.Vec3{Y: -1}},
+	{normal: geom.Vec3{X: 1}, across: geom.Vec3{Z: 1}, down: geom.Vec3{Y: -1}},
+	{normal: geom.Vec3{Y: 1}, across: geom.Vec3{X: 1}, down: geom.Vec3{Z: -1}},
+	{normal: geom.Vec3{Y: -1}, across: geom.Vec3{X: 1}, down: geom.Vec3{Z: 1}},
+}
+
+// Box returns a box with the given width, height and depth. Each face has its
+// own vertices, so that its normals are flat, and is covered by the whole
+// texture.
+func Box(size geom.Vec3) *canvas.IndexedTriangleList {
+	b := newBuilder()
+	half := size.Scale(0.5)
+	for _, face := range boxFaces {
+		center := face.normal.Mul(half)
+		across, down := face.across.Mul(size), face.down.Mul(size)
+		b.grid(1, 1, func(i, j int) (geom.Vec3, geom.Vec3) {
+			pos := center.
+				Add(across.Scale(float32(i) - 0.5)).
+				Add(down.Scale(float32(j) - 0.5))
+			return pos, face.normal
+		})
+	}
+	return b.list
+}
+
+// Plane returns a flat rectangle facing up, with the given width along the
+// X-axis and depth along the Z-axis, split into a grid of columns and rows.
+// The texture is laid out as seen from above, with its top at the far edge.
+func Plane(width, depth float32, columns, rows int) *canvas.IndexedTriangleList {
+	columns, rows = atLeast(columns, 1), atLeast(rows, 1)
+	b := newBuilder()
+	b.grid(columns, rows, func(i, j int) (geom.Vec3, geom.Vec3) {
+		pos := geom.Vec3{
+			X: width * (float32(i)/float32(columns) - 0.5),
+			Z: depth * (0.5 - float32(j)/float32(rows)),
+		}
+		return pos, geom.Vec3{Y: 1}
+	})
+	return b.list
+}
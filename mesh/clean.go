@@ -0,0 +1,259 @@
+package mesh
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// triangles returns the triangles of a shape, and whether it is made of
+// triangles at all.
+func triangles(list *canvas.IndexedTriangleList) ([][3]int, bool) {
+	switch list.Topology {
+	case canvas.TriangleList, canvas.TriangleStrip, canvas.TriangleFan:
+		return list.Triangles(), true
+	default:
+		return nil, false
+	}
+}
+
+// setTriangles replaces the indices of a shape with a list of triangles.
+func setTriangles(list *canvas.IndexedTriangleList, tris [][3]int) {
+	list.Indices = make([]int, 0, len(tris)*3)
+	for _, tri := range tris {
+		list.Indices = append(list.Indices, tri[0], tri[1], tri[2])
+	}
+	list.Topology = canvas.TriangleList
+}
+
+// selectVertices replaces the vertices of a shape, along with any attributes
+// that it has, with the vertices at the given indices, in order. Indices are
+// left for the caller to update.
+func selectVertices(list *canvas.IndexedTriangleList, order []int) {
+	vertices := make([]geom.Vec3, len(order))
+	for i, index := range order {
+		vertices[i] = list.Vertices[index]
+	}
+	list.Vertices = vertices
+	if list.TexCoords != nil {
+		texCoords := make([]geom.Vec2, len(order))
+		for i, index := range order {
+			texCoords[i] = list.TexCoords[index]
+		}
+		list.TexCoords = texCoords
+	}
+	list.Normals = selectVec3s(list.Normals, order)
+	list.Colors = selectVec3s(list.Colors, order)
+}
+
+func selectVec3s(values []geom.Vec3, order []int) []geom.Vec3 {
+	if values == nil {
+		return nil
+	}
+	selected := make([]geom.Vec3, len(order))
+	for i, index := range order {
+		selected[i] = values[index]
+	}
+	return selected
+}
+
+// Weld merges vertices whose positions, and any texture coordinates, normals
+// and colors, are all within epsilon of each other, and returns the number of
+// vertices removed. Triangles may become degenerate as a result.
+func Weld(list *canvas.IndexedTriangleList, epsilon float32) int {
+	grid := newVertexGrid(epsilon)
+	remap := make([]int, len(list.Vertices))
+	var order []int
+	for i, v := range list.Vertices {
+		remap[i] = grid.find(v, func(kept int) bool {
+			return sameVertex(list, order[kept], i, epsilon)
+		})
+		if remap[i] < 0 {
+			remap[i] = len(order)
+			grid.add(v, len(order))
+			order = append(order, i)
+		}
+	}
+
+	removed := len(list.Vertices) - len(order)
+	selectVertices(list, order)
+	for i, index := range list.Indices {
+		list.Indices[i] = remap[index]
+	}
+	return removed
+}
+
+// vertexGrid finds points near each other by sorting them into cells of the
+// size of epsilon, so that those within epsilon of each other are in the same
+// or adjacent cells. Without an epsilon, only points at exactly the same
+// position are in the same cell, counting -0 and +0 as the same.
+type vertexGrid struct {
+	epsilon float32
+	cells   map[[3]int64][]int
+}
+
+func newVertexGrid(epsilon float32) *vertexGrid {
+	return &vertexGrid{epsilon: epsilon, cells: make(map[[3]int64][]int)}
+}
+
+func (g *vertexGrid) cell(v geom.Vec3) [3]int64 {
+	if g.epsilon <= 0 {
+		return [3]int64{bits(v.X), bits(v.Y), bits(v.Z)}
+	}
+	return [3]int64{
+		int64(math.Floor(float64(v.X / g.epsilon))),
+		int64(math.Floor(float64(v.Y / g.epsilon))),
+		int64(math.Floor(float64(v.Z / g.epsilon))),
+	}
+}
+
+// bits returns the bits of x, with -0 turned into +0.
+func bits(x float32) int64 {
+	if x == 0 {
+		x = 0
+	}
+	return int64(math.Float32bits(x))
+}
+
+// add adds a point with the given id.
+func (g *vertexGrid) add(v geom.Vec3, id int) {
+	cell := g.cell(v)
+	g.cells[cell] = append(g.cells[cell], id)
+}
+
+// find returns the id of the first point added near v for which match returns
+// true, or -1 if there is none.
+func (g *vertexGrid) find(v geom.Vec3, match func(id int) bool) int {
+	cell := g.cell(v)
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for dz := int64(-1); dz <= 1; dz++ {
+				for _, id := range g.cells[[3]int64{cell[0] + dx, cell[1] + dy, cell[2] + dz}] {
+					if match(id) {
+						return id
+					}
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// sameVertex returns whether all of the attributes of two vertices are within
+// epsilon of each other.
+func sameVertex(list *canvas.IndexedTriangleList, a, b int, epsilon float32) bool {
+	if list.Vertices[a].Distance(list.Vertices[b]) > epsilon {
+		return false
+	}
+	if list.TexCoords != nil && list.TexCoords[a].Distance(list.TexCoords[b]) > epsilon {
+		return false
+	}
+	if list.Normals != nil && list.Normals[a].Distance(list.Normals[b]) > epsilon {
+		return false
+	}
+	if list.Colors != nil && list.Colors[a].Distance(list.Colors[b]) > epsilon {
+		return false
+	}
+	return true
+}
+
+// degenerateTolerance is how small the area of a triangle may be, relative to
+// the square of its longest edge, before it counts as having no area. It is
+// well above the rounding error of float32 positions.
+const degenerateTolerance = 1e-6
+
+// RemoveDegenerateTriangles removes triangles with no area, because two of
+// their corners are the same or all three lie on a line, and returns the
+// number removed. Slivers whose area is tiny compared to their size count as
+// having none, since rounding rarely leaves collinear corners exactly in line.
+func RemoveDegenerateTriangles(list *canvas.IndexedTriangleList) int {
+	tris, ok := triangles(list)
+	if !ok {
+		return 0
+	}
+	kept := tris[:0]
+	for _, tri := range tris {
+		v0, v1, v2 := list.Vertices[tri[0]], list.Vertices[tri[1]], list.Vertices[tri[2]]
+		e0, e1, e2 := v1.Sub(v0), v2.Sub(v0), v2.Sub(v1)
+		longest := e0.LengthSquared()
+		if l := e1.LengthSquared(); l > longest {
+			longest = l
+		}
+		if l := e2.LengthSquared(); l > longest {
+			longest = l
+		}
+		// The length of the cross product is twice the area
+		area := e0.Cross(e1).Length() / 2
+		if area > degenerateTolerance*longest {
+			kept = append(kept, tri)
+		}
+	}
+	setTriangles(list, kept)
+	return len(tris) - len(kept)
+}
+
+// RemoveDuplicateTriangles removes triangles that use the same vertices as an
+// earlier triangle, starting from any corner, and returns the number removed.
+// Triangles facing the opposite way are not duplicates, so two-sided surfaces
+// are kept.
+func RemoveDuplicateTriangles(list *canvas.IndexedTriangleList) int {
+	tris, ok := triangles(list)
+	if !ok {
+		return 0
+	}
+	seen := make(map[[3]int]bool)
+	kept := tris[:0]
+	for _, tri := range tris {
+		// Rotate the smallest index to the front, which keeps the winding
+		key := tri
+		for key[0] > key[1] || key[0] > key[2] {
+			key = [3]int{key[1], key[2], key[0]}
+		}
+		if !seen[key] {
+			seen[key] = true
+			kept = append(kept, tri)
+		}
+	}
+	setTriangles(list, kept)
+	return len(tris) - len(kept)
+}
+
+// RemoveUnusedVertices removes vertices that no primitive refers to, and
+// returns the number removed.
+func RemoveUnusedVertices(list *canvas.IndexedTriangleList) int {
+	remap := make([]int, len(list.Vertices))
+	for i := range remap {
+		remap[i] = -1
+	}
+	var order []int
+	for _, index := range list.Indices {
+		if remap[index] < 0 {
+			remap[index] = len(order)
+			order = append(order, index)
+		}
+	}
+
+	removed := len(list.Vertices) - len(order)
+	selectVertices(list, order)
+	for i, index := range list.Indices {
+		list.Indices[i] = remap[index]
+	}
+	return removed
+}
+
+// FlipWinding turns every triangle of the shape to face the other way, and
+// reverses its normals to match.
+func FlipWinding(list *canvas.IndexedTriangleList) {
+	tris, ok := triangles(list)
+	if !ok {
+		return
+	}
+	for i, tri := range tris {
+		tris[i] = [3]int{tri[0], tri[2], tri[1]}
+	}
+	setTriangles(list, tris)
+	for i, n := range list.Normals {
+		list.Normals[i] = n.Negate()
+	}
+}
@@ -0,0 +1,161 @@
+package mesh
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+func TestWeld(t *testing.T) {
+	negZero := float32(math.Copysign(0, -1))
+	tests := []struct {
+		name        string
+		vertices    []geom.Vec3
+		texCoords   []geom.Vec2
+		epsilon     float32
+		wantRemoved int
+		wantIndices []int
+	}{
+		{
+			name:        "exact",
+			vertices:    []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: 1}},
+			wantRemoved: 1,
+			wantIndices: []int{0, 1, 2, 1},
+		},
+		{
+			name:        "signed zeros",
+			vertices:    []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: negZero, Z: negZero}},
+			wantRemoved: 1,
+			wantIndices: []int{0, 1, 2, 0},
+		},
+		{
+			name:        "within epsilon",
+			vertices:    []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: 1.0005, Y: -0.0005}},
+			epsilon:     0.001,
+			wantRemoved: 1,
+			wantIndices: []int{0, 1, 2, 1},
+		},
+		{
+			name:        "outside epsilon",
+			vertices:    []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: 1.002}},
+			epsilon:     0.001,
+			wantRemoved: 0,
+			wantIndices: []int{0, 1, 2, 3},
+		},
+		{
+			name:        "different texture coordinates",
+			vertices:    []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: 1}},
+			texCoords:   []geom.Vec2{{}, {X: 1}, {Y: 1}, {X: 0.5}},
+			wantRemoved: 0,
+			wantIndices: []int{0, 1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		list := &canvas.IndexedTriangleList{
+			Vertices:  tt.vertices,
+			TexCoords: tt.texCoords,
+			Indices:   []int{0, 1, 2, 3},
+			Topology:  canvas.PointList,
+		}
+		if got := Weld(list, tt.epsilon); got != tt.wantRemoved {
+			t.Errorf("%s: Weld() = %d, want %d", tt.name, got, tt.wantRemoved)
+		}
+		if !reflect.DeepEqual(list.Indices, tt.wantIndices) {
+			t.Errorf("%s: indices = %v, want %v", tt.name, list.Indices, tt.wantIndices)
+		}
+		if len(list.Vertices) != 4-tt.wantRemoved {
+			t.Errorf("%s: %d vertices left, want %d", tt.name, len(list.Vertices), 4-tt.wantRemoved)
+		}
+	}
+}
+
+func TestRemoveDegenerateTriangles(t *testing.T) {
+	list := &canvas.IndexedTriangleList{
+		Vertices: []geom.Vec3{
+			{}, {X: 1}, {Y: 1},
+			// Nearly on the line from the origin to (1, 0, 0), as if rounded
+			{X: 0.3, Y: 1e-9},
+			// Thin, but clearly not a line
+			{X: 100, Y: 1},
+		},
+		Indices: []int{
+			0, 2, 1, // fine
+			0, 0, 1, // repeated corner
+			0, 3, 1, // sliver
+			0, 4, 1, // thin
+		},
+		Topology: canvas.TriangleList,
+	}
+	if got := RemoveDegenerateTriangles(list); got != 2 {
+		t.Errorf("RemoveDegenerateTriangles() = %d, want 2", got)
+	}
+	if want := []int{0, 2, 1, 0, 4, 1}; !reflect.DeepEqual(list.Indices, want) {
+		t.Errorf("indices = %v, want %v", list.Indices, want)
+	}
+}
+
+func TestRemoveDuplicateTriangles(t *testing.T) {
+	list := &canvas.IndexedTriangleList{
+		Vertices: make([]geom.Vec3, 4),
+		Indices: []int{
+			0, 1, 2,
+			1, 2, 0, // the same, starting from another corner
+			0, 2, 1, // facing the other way
+			1, 2, 3,
+			0, 1, 2,
+		},
+		Topology: canvas.TriangleList,
+	}
+	if got := RemoveDuplicateTriangles(list); got != 2 {
+		t.Errorf("RemoveDuplicateTriangles() = %d, want 2", got)
+	}
+	if want := []int{0, 1, 2, 0, 2, 1, 1, 2, 3}; !reflect.DeepEqual(list.Indices, want) {
+		t.Errorf("indices = %v, want %v", list.Indices, want)
+	}
+}
+
+func TestRemoveUnusedVertices(t *testing.T) {
+	list := &canvas.IndexedTriangleList{
+		Vertices: []geom.Vec3{{X: 0}, {X: 1}, {X: 2}, {X: 3}, {X: 4}},
+		Normals:  []geom.Vec3{{Y: 0}, {Y: 1}, {Y: 2}, {Y: 3}, {Y: 4}},
+		Indices:  []int{4, 1, 3},
+		Topology: canvas.TriangleList,
+	}
+	if got := RemoveUnusedVertices(list); got != 2 {
+		t.Errorf("RemoveUnusedVertices() = %d, want 2", got)
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(list.Indices, want) {
+		t.Errorf("indices = %v, want %v", list.Indices, want)
+	}
+	if want := []geom.Vec3{{X: 4}, {X: 1}, {X: 3}}; !reflect.DeepEqual(list.Vertices, want) {
+		t.Errorf("vertices = %v, want %v", list.Vertices, want)
+	}
+	if want := []geom.Vec3{{Y: 4}, {Y: 1}, {Y: 3}}; !reflect.DeepEqual(list.Normals, want) {
+		t.Errorf("normals = %v, want %v", list.Normals, want)
+	}
+}
+
+func TestFlipWinding(t *testing.T) {
+	list := &canvas.IndexedTriangleList{
+		Vertices: make([]geom.Vec3, 4),
+		Normals:  []geom.Vec3{{Z: 1}, {Z: 1}, {Z: 1}, {Z: 1}},
+		Indices:  []int{0, 1, 2, 3},
+		Topology: canvas.TriangleStrip,
+	}
+	FlipWinding(list)
+	if list.Topology != canvas.TriangleList {
+		t.Errorf("topology = %v, want a triangle list", list.Topology)
+	}
+	// The strip's second triangle is (2, 1, 3)
+	if want := []int{0, 2, 1, 2, 3, 1}; !reflect.DeepEqual(list.Indices, want) {
+		t.Errorf("indices = %v, want %v", list.Indices, want)
+	}
+	for i, n := range list.Normals {
+		if n != (geom.Vec3{Z: -1}) {
+			t.Errorf("normal %d = %v, want %v", i, n, geom.Vec3{Z: -1})
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package mesh generates common shapes as IndexedTriangleLists, and cleans up
+// and computes normals for existing ones.
+//
+// Every generated shape is centred on the origin, with positions, texture
+// coordinates and normals for each vertex. Front faces point away from the
+// inside of the shape, so that back faces can be culled.
+//
+// The processing functions change shapes in place. Shapes made of triangle
+// strips or fans are turned into triangle lists, and those made of points or
+// lines are left as they are, except by Weld and RemoveUnusedVertices.
+package mesh
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// builder adds vertices and triangles to a shape.
+type builder struct {
+	list *canvas.IndexedTriangleList
+}
+
+func newBuilder() *builder {
+	return &builder{list: &canvas.IndexedTriangleList{
+		Vertices:  make([]geom.Vec3, 0),
+		TexCoords: make([]geom.Vec2, 0),
+		Normals:   make([]geom.Vec3, 0),
+		Indices:   make([]int, 0),
+		Topology:  canvas.TriangleList,
+	}}
+}
+
+// vertex adds a vertex to the shape and returns its index.
+func (b *builder) vertex(pos, normal geom.Vec3, texCoord geom.Vec2) int {
+	b.list.Vertices = append(b.list.Vertices, pos)
+	b.list.Normals = append(b.list.Normals, normal)
+	b.list.TexCoords = append(b.list.TexCoords, texCoord)
+	return len(b.list.Vertices) - 1
+}
+
+// grid adds a surface made of a grid of vertices, with the given number of
+// columns and rows of quads between them. point returns the position and
+// normal of the vertex in column i and row j, and texture coordinates go from
+// 0 to 1 across the columns and down the rows. The front faces are on the side
+// where moving along the columns and then down the rows turns clockwise.
+// Triangles that collapse because a row shrinks to a single point, as at the
+// poles of a sphere, are left out.
+func (b *builder) grid(columns, rows int, point func(i, j int) (geom.Vec3, geom.Vec3)) {
+	base := len(b.list.Vertices)
+	side := columns + 1
+	for j := 0; j <= rows; j++ {
+		for i := 0; i <= columns; i++ {
+			pos, normal := point(i, j)
+			b.vertex(pos, normal, geom.Vec2{
+				X: float32(i) / float32(columns),
+				Y: float32(j) / float32(rows),
+			})
+		}
+	}
+
+	vertices := b.list.Vertices
+	for j := 0; j < rows; j++ {
+		for i := 0; i < columns; i++ {
+			i0 := base + j*side + i
+			i1, i2, i3 := i0+1, i0+side, i0+side+1
+			if vertices[i0] != vertices[i1] {
+				b.list.Indices = append(b.list.Indices, i0, i1, i2)
+			}
+			if vertices[i2] != vertices[i3] {
+				b.list.Indices = append(b.list.Indices, i1, i3, i2)
+			}
+		}
+	}
+}
+
+// disc adds a flat circle at height y, facing up or down.
+func (b *builder) disc(y, radius float32, segments int, up bool) {
+	normal := geom.Vec3{Y: -1}
+	if up {
+		normal.Y = 1
+	}
+	center := b.vertex(geom.Vec3{Y: y}, normal, geom.Vec2{X: 0.5, Y: 0.5})
+	for i := 0; i <= segments; i++ {
+		sin, cos := turn(i, segments)
+		// Texture coordinates are laid out as seen from the side that the disc
+		// faces
+		texCoord := geom.Vec2{X: 0.5 + cos/2, Y: 0.5 + sin/2}
+		if up {
+			texCoord.Y = 0.5 - sin/2
+		}
+		b.vertex(geom.Vec3{X: radius * cos, Y: y, Z: radius * sin}, normal, texCoord)
+	}
+	for i := 1; i <= segments; i++ {
+		if up {
+			b.list.Indices = append(b.list.Indices, center, center+i+1, center+i)
+		} else {
+			b.list.Indices = append(b.list.Indices, center, center+i, center+i+1)
+		}
+	}
+}
+
+// turn returns the sine and cosine of the angle i/n of the way around a
+// circle. Whole turns are exactly zero, so that vertices on either side of a
+// seam are identical.
+func turn(i, n int) (float32, float32) {
+	if i%n == 0 {
+		return 0, 1
+	}
+	return sinCos(float64(i) / float64(n) * 2 * math.Pi)
+}
+
+// sinCos returns the sine and cosine of the angle.
+func sinCos(angle float64) (float32, float32) {
+	sin, cos := math.Sincos(angle)
+	return float32(sin), float32(cos)
+}
+
+// atLeast returns n, or min if n is smaller.
+func atLeast(n, min int) int {
+	if n < min {
+		return min
+	}
+	return n
+}
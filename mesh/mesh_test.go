@@ -0,0 +1,67 @@
+package mesh
+
+import (
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+func TestSeams(t *testing.T) {
+	// Each shape starts with a grid whose first and last columns meet at the
+	// seam, and whose rows are the given number of quads apart
+	const segments = 7
+	tests := []struct {
+		name string
+		list *canvas.IndexedTriangleList
+		rows int
+	}{
+		{"UVSphere", UVSphere(1, segments, 5), 5},
+		{"Capsule", Capsule(1, 2, segments, 3), 7},
+		{"Cylinder", Cylinder(1, 2, segments), 1},
+		{"Cone", Cone(1, 2, segments), 1},
+		{"Torus", Torus(2, 0.5, segments, 6), 6},
+	}
+	for _, tt := range tests {
+		for j := 0; j <= tt.rows; j++ {
+			first := j * (segments + 1)
+			last := first + segments
+			if tt.list.Vertices[first] != tt.list.Vertices[last] {
+				t.Errorf("%s: row %d starts at %v and ends at %v", tt.name, j, tt.list.Vertices[first], tt.list.Vertices[last])
+			}
+			if tt.list.Normals[first] != tt.list.Normals[last] {
+				t.Errorf("%s: row %d normals start at %v and end at %v", tt.name, j, tt.list.Normals[first], tt.list.Normals[last])
+			}
+		}
+	}
+}
+
+func TestWinding(t *testing.T) {
+	// The front of every triangle faces the same way as its vertices' normals,
+	// which point out of the shape
+	tests := []struct {
+		name string
+		list *canvas.IndexedTriangleList
+	}{
+		{"Box", Box(geom.Vec3{X: 1, Y: 2, Z: 3})},
+		{"Plane", Plane(2, 1, 3, 2)},
+		{"UVSphere", UVSphere(1, 8, 6)},
+		{"Capsule", Capsule(1, 2, 8, 3)},
+		{"Icosphere", Icosphere(1, 2)},
+		{"Cylinder", Cylinder(1, 2, 8)},
+		{"Cone", Cone(1, 2, 8)},
+		{"Torus", Torus(2, 0.5, 8, 6)},
+	}
+	for _, tt := range tests {
+		for i, tri := range tt.list.Triangles() {
+			var normal geom.Vec3
+			for _, index := range tri {
+				normal = normal.Add(tt.list.Normals[index])
+			}
+			if faceNormal(tt.list, tri).Dot(normal) <= 0 {
+				t.Errorf("%s: triangle %d faces inwards", tt.name, i)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package mesh
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// FlatNormals gives every triangle of the shape its own vertices, with the
+// normal of the triangle, so that it is shaded as a flat surface.
+func FlatNormals(list *canvas.IndexedTriangleList) {
+	tris, ok := triangles(list)
+	if !ok {
+		return
+	}
+	order := make([]int, 0, len(tris)*3)
+	normals := make([]geom.Vec3, 0, len(tris)*3)
+	for i, tri := range tris {
+		normal := faceNormal(list, tri)
+		for k, index := range tri {
+			tris[i][k] = len(order)
+			order = append(order, index)
+			normals = append(normals, normal)
+		}
+	}
+	selectVertices(list, order)
+	list.Normals = normals
+	setTriangles(list, tris)
+}
+
+// SmoothNormals gives the vertices of the shape normals that blend smoothly
+// between the triangles around them, weighted by the angle of each triangle's
+// corner. Triangles that meet at an angle of more than creaseAngle radians
+// are not blended, so that hard edges stay sharp, and vertices on those edges
+// are split. Vertices that share a position are blended even if they have
+// different texture coordinates, as are those a tiny fraction of the size of
+// the shape apart, which are usually meant to be at the same position.
+func SmoothNormals(list *canvas.IndexedTriangleList, creaseAngle float32) {
+	tris, ok := triangles(list)
+	if !ok {
+		return
+	}
+	minCos := float32(math.Cos(float64(creaseAngle)))
+	positions := positionGroups(list)
+
+	type corner struct{ tri, k int }
+	normals := make([]geom.Vec3, len(tris))
+	angles := make([][3]float32, len(tris))
+	corners := make(map[int][]corner)
+	for i, tri := range tris {
+		normals[i] = faceNormal(list, tri)
+		for k, index := range tri {
+			angles[i][k] = cornerAngle(list, tri, k)
+			pos := positions[index]
+			corners[pos] = append(corners[pos], corner{tri: i, k: k})
+		}
+	}
+
+	// Corners of the same vertex that end up with the same normal share a
+	// vertex, and the rest are split off into new ones
+	type vertexNormal struct {
+		index  int
+		normal geom.Vec3
+	}
+	vertices := make(map[vertexNormal]int)
+	var order []int
+	var vertexNormals []geom.Vec3
+	for i, tri := range tris {
+		for k, index := range tri {
+			var sum geom.Vec3
+			for _, c := range corners[positions[index]] {
+				if normals[c.tri].Dot(normals[i]) >= minCos {
+					sum = sum.Add(normals[c.tri].Scale(angles[c.tri][c.k]))
+				}
+			}
+			normal := normals[i]
+			if sum.LengthSquared() > 0 {
+				normal = sum.Normalize()
+			}
+
+			key := vertexNormal{index: index, normal: normal}
+			vertex, ok := vertices[key]
+			if !ok {
+				vertex = len(order)
+				vertices[key] = vertex
+				order = append(order, index)
+				vertexNormals = append(vertexNormals, normal)
+			}
+			tris[i][k] = vertex
+		}
+	}
+	selectVertices(list, order)
+	list.Normals = vertexNormals
+	setTriangles(list, tris)
+}
+
+// positionGroups numbers the distinct positions of the shape's vertices, and
+// returns the number of each vertex's position. Positions within a millionth of
+// the size of the shape of each other count as the same.
+func positionGroups(list *canvas.IndexedTriangleList) []int {
+	bounds := list.Bounds()
+	size := bounds.Max.Sub(bounds.Min)
+	epsilon := float32(math.Max(float64(size.X), math.Max(float64(size.Y), float64(size.Z)))) * 1e-6
+
+	grid := newVertexGrid(epsilon)
+	groups := make([]int, len(list.Vertices))
+	var first []int
+	for i, v := range list.Vertices {
+		groups[i] = grid.find(v, func(group int) bool {
+			return list.Vertices[first[group]].Distance(v) <= epsilon
+		})
+		if groups[i] < 0 {
+			groups[i] = len(first)
+			grid.add(v, len(first))
+			first = append(first, i)
+		}
+	}
+	return groups
+}
+
+// faceNormal returns the unit normal on the front of a triangle, or zero if it
+// has no area.
+func faceNormal(list *canvas.IndexedTriangleList, tri [3]int) geom.Vec3 {
+	v0, v1, v2 := list.Vertices[tri[0]], list.Vertices[tri[1]], list.Vertices[tri[2]]
+	normal := v1.Sub(v0).Cross(v2.Sub(v0))
+	if normal.LengthSquared() == 0 {
+		return geom.Vec3{}
+	}
+	return normal.Normalize()
+}
+
+// cornerAngle returns the angle in radians of the triangle's kth corner.
+func cornerAngle(list *canvas.IndexedTriangleList, tri [3]int, k int) float32 {
+	v := list.Vertices[tri[k]]
+	a := list.Vertices[tri[(k+1)%3]].Sub(v)
+	b := list.Vertices[tri[(k+2)%3]].Sub(v)
+	if a.LengthSquared() == 0 || b.LengthSquared() == 0 {
+		return 0
+	}
+	cos := a.Normalize().Dot(b.Normalize())
+	return float32(math.Acos(math.Max(-1, math.Min(float64(cos), 1))))
+}
@@ -0,0 +1,63 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+const epsilon = 1e-5
+
+// ridge returns two triangles meeting at a right angle along the Z-axis, each
+// with its own copy of the shared edge. The copies on the second triangle are
+// moved by offset.
+func ridge(offset float32) *canvas.IndexedTriangleList {
+	return &canvas.IndexedTriangleList{
+		Vertices: []geom.Vec3{
+			{}, {Z: 1}, {X: 1},
+			{X: offset}, {X: offset, Z: 1}, {Y: 1},
+		},
+		Indices:  []int{0, 1, 2, 3, 5, 4},
+		Topology: canvas.TriangleList,
+	}
+}
+
+func TestFlatNormals(t *testing.T) {
+	list := ridge(0)
+	FlatNormals(list)
+	want := []geom.Vec3{{Y: 1}, {Y: 1}, {Y: 1}, {X: 1}, {X: 1}, {X: 1}}
+	for i, n := range list.Normals {
+		if !n.ApproxEqual(want[i], epsilon) {
+			t.Errorf("normal %d = %v, want %v", i, n, want[i])
+		}
+	}
+}
+
+func TestSmoothNormals(t *testing.T) {
+	// The edge between the triangles is blended, and their far corners are not
+	edge := geom.Vec3{X: 1, Y: 1}.Normalize()
+	tests := []struct {
+		name   string
+		offset float32
+		crease float32
+		want   []geom.Vec3
+	}{
+		{"shared edge", 0, math.Pi, []geom.Vec3{edge, edge, {Y: 1}, {X: 1}}},
+		{"nearly shared edge", 1e-9, math.Pi, []geom.Vec3{edge, edge, {Y: 1}, {X: 1}}},
+		{"crease", 0, math.Pi / 4, []geom.Vec3{{Y: 1}, {Y: 1}, {Y: 1}, {X: 1}}},
+	}
+	for _, tt := range tests {
+		list := ridge(tt.offset)
+		SmoothNormals(list, tt.crease)
+		// Corners of the first triangle, and the far corner of the second
+		tris := list.Triangles()
+		corners := []int{tris[0][0], tris[0][1], tris[0][2], tris[1][1]}
+		for i, index := range corners {
+			if got := list.Normals[index]; !got.ApproxEqual(tt.want[i], epsilon) {
+				t.Errorf("%s: normal of corner %d = %v, want %v", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package mesh
+
+import (
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Cylinder returns a closed cylinder standing on the Y-axis, with the given
+// radius and height, and the given number of segments around it. The texture
+// wraps around the side once, and covers each end.
+func Cylinder(radius, height float32, segments int) *canvas.IndexedTriangleList {
+	segments = atLeast(segments, 3)
+	b := newBuilder()
+	b.grid(segments, 1, func(i, j int) (geom.Vec3, geom.Vec3) {
+		sin, cos := turn(i, segments)
+		pos := geom.Vec3{X: radius * cos, Y: height * (0.5 - float32(j)), Z: radius * sin}
+		return pos, geom.Vec3{X: cos, Z: sin}
+	})
+	b.disc(height/2, radius, segments, true)
+	b.disc(-height/2, radius, segments, false)
+	return b.list
+}
+
+// Cone returns a closed cone standing on the Y-axis, with the given base radius
+// and height, and the given number of segments around it. The apex is at the
+// top, and the texture wraps around the side once and covers the base.
+func Cone(radius, height float32, segments int) *canvas.IndexedTriangleList {
+	segments = atLeast(segments, 3)
+	b := newBuilder()
+	// The side leans in by the same angle all the way up, so its normals only
+	// depend on the angle around the axis
+	slope := geom.Vec2{X: height, Y: radius}.Normalize()
+	b.grid(segments, 1, func(i, j int) (geom.Vec3, geom.Vec3) {
+		sin, cos := turn(i, segments)
+		r := radius * float32(j)
+		pos := geom.Vec3{X: r * cos, Y: height * (0.5 - float32(j)), Z: r * sin}
+		return pos, geom.Vec3{X: slope.X * cos, Y: slope.Y, Z: slope.X * sin}
+	})
+	b.disc(-height/2, radius, segments, false)
+	return b.list
+}
+
+// Torus returns a ring lying flat around the Y-axis. majorRadius is the
+// distance from the centre to the middle of the tube, and minorRadius is the
+// radius of the tube. The texture wraps once around the ring and once around
+// the tube.
+func Torus(majorRadius, minorRadius float32, majorSegments, minorSegments int) *canvas.IndexedTriangleList {
+	majorSegments, minorSegments = atLeast(majorSegments, 3), atLeast(minorSegments, 3)
+	b := newBuilder()
+	b.grid(majorSegments, minorSegments, func(i, j int) (geom.Vec3, geom.Vec3) {
+		sinTheta, cosTheta := turn(i, majorSegments)
+		// Start on the outside of the ring and go down first, so that the rows
+		// run down the outside of the tube
+		sinPhi, cosPhi := turn(-j, minorSegments)
+		normal := geom.Vec3{X: cosPhi * cosTheta, Y: sinPhi, Z: cosPhi * sinTheta}
+		center := geom.Vec3{X: majorRadius * cosTheta, Z: majorRadius * sinTheta}
+		return center.Add(normal.Scale(minorRadius)), normal
+	})
+	return b.list
+}
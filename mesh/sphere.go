@@ -0,0 +1,167 @@
+package mesh
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// UVSphere returns a sphere made of rings of vertices from pole to pole, with
+// the given number of segments around it. The texture is wrapped around it
+// with an equirectangular projection, with its top at the north pole.
+func UVSphere(radius float32, segments, rings int) *canvas.IndexedTriangleList {
+	segments, rings = atLeast(segments, 3), atLeast(rings, 2)
+	b := newBuilder()
+	b.grid(segments, rings, func(i, j int) (geom.Vec3, geom.Vec3) {
+		normal := sphereNormal(i, segments, j, rings)
+		return normal.Scale(radius), normal
+	})
+	return b.list
+}
+
+// Capsule returns a cylinder with the given radius and length along the
+// Y-axis, capped with hemispheres, so that its total height is length plus
+// twice the radius. Each hemisphere has the given number of rings. The
+// texture is wrapped around it from top to bottom.
+func Capsule(radius, length float32, segments, rings int) *canvas.IndexedTriangleList {
+	segments, rings = atLeast(segments, 3), atLeast(rings, 1)
+	b := newBuilder()
+	// The rows go down the top hemisphere, along the side, and down the bottom
+	// hemisphere, which are each half of a sphere with twice as many rings
+	b.grid(segments, 2*rings+1, func(i, j int) (geom.Vec3, geom.Vec3) {
+		offset := length / 2
+		if j > rings {
+			j--
+			offset = -offset
+		}
+		normal := sphereNormal(i, segments, j, 2*rings)
+		return normal.Scale(radius).Add(geom.Vec3{Y: offset}), normal
+	})
+	return b.list
+}
+
+// sphereNormal returns the point on a unit sphere at step i of the given number
+// of segments around it, on ring j of the given number from the north pole to
+// the south.
+func sphereNormal(i, segments, j, rings int) geom.Vec3 {
+	// The poles are placed exactly, so that their rings collapse to a point
+	switch j {
+	case 0:
+		return geom.Vec3{Y: 1}
+	case rings:
+		return geom.Vec3{Y: -1}
+	}
+	sinTheta, cosTheta := turn(i, segments)
+	sinPhi, cosPhi := sinCos(float64(j) / float64(rings) * math.Pi)
+	return geom.Vec3{X: sinPhi * cosTheta, Y: cosPhi, Z: sinPhi * sinTheta}
+}
+
+// Icosphere returns a sphere made by repeatedly splitting the faces of an
+// icosahedron, which spreads its vertices more evenly than UVSphere. Each
+// subdivision has four times as many triangles as the last. The texture is
+// mapped in the same way as UVSphere, with vertices along the seam duplicated
+// so that it does not wrap backwards.
+func Icosphere(radius float32, subdivisions int) *canvas.IndexedTriangleList {
+	// The vertices of an icosahedron lie on three golden rectangles
+	phi := float32((1 + math.Sqrt(5)) / 2)
+	points := []geom.Vec3{
+		{X: -1, Y: phi}, {X: 1, Y: phi}, {X: -1, Y: -phi}, {X: 1, Y: -phi},
+		{Y: -1, Z: phi}, {Y: 1, Z: phi}, {Y: -1, Z: -phi}, {Y: 1, Z: -phi},
+		{X: phi, Z: -1}, {X: phi, Z: 1}, {X: -phi, Z: -1}, {X: -phi, Z: 1},
+	}
+	for i := range points {
+		points[i] = points[i].Normalize()
+	}
+	faces := [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+
+	for n := 0; n < subdivisions; n++ {
+		// Edges are shared by two faces, which must share their midpoint
+		midpoints := make(map[[2]int]int)
+		midpoint := func(a, b int) int {
+			key := [2]int{a, b}
+			if a > b {
+				key = [2]int{b, a}
+			}
+			if index, ok := midpoints[key]; ok {
+				return index
+			}
+			points = append(points, points[a].Add(points[b]).Normalize())
+			midpoints[key] = len(points) - 1
+			return len(points) - 1
+		}
+
+		split := make([][3]int, 0, len(faces)*4)
+		for _, f := range faces {
+			ab, bc, ca := midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])
+			split = append(split,
+				[3]int{f[0], ab, ca},
+				[3]int{f[1], bc, ab},
+				[3]int{f[2], ca, bc},
+				[3]int{ab, bc, ca},
+			)
+		}
+		faces = split
+	}
+
+	b := newBuilder()
+	for _, p := range points {
+		b.vertex(p.Scale(radius), p, sphereTexCoord(p))
+	}
+	// Seam copies of vertices, with U increased by one
+	seam := make(map[int]int)
+	for _, f := range faces {
+		// Orient each face to match the grid shapes, whose front faces are on
+		// the side of the normal given by the cross product of the first two
+		// edges
+		if points[f[1]].Sub(points[f[0]]).Cross(points[f[2]].Sub(points[f[0]])).Dot(points[f[0]]) < 0 {
+			f[1], f[2] = f[2], f[1]
+		}
+
+		// A face whose texture coordinates span more than half of the texture
+		// crosses the seam, so its vertices on the near side are moved across
+		texCoords := b.list.TexCoords
+		minU := min3(texCoords[f[0]].X, texCoords[f[1]].X, texCoords[f[2]].X)
+		maxU := max3(texCoords[f[0]].X, texCoords[f[1]].X, texCoords[f[2]].X)
+		if maxU-minU > 0.5 {
+			for k, index := range f {
+				if texCoords[index].X >= 0.5 {
+					continue
+				}
+				copied, ok := seam[index]
+				if !ok {
+					texCoord := geom.Vec2{X: texCoords[index].X + 1, Y: texCoords[index].Y}
+					copied = b.vertex(b.list.Vertices[index], b.list.Normals[index], texCoord)
+					seam[index] = copied
+				}
+				f[k] = copied
+			}
+		}
+		b.list.Indices = append(b.list.Indices, f[0], f[1], f[2])
+	}
+	return b.list
+}
+
+// sphereTexCoord returns the texture coordinates of a point on a unit sphere,
+// matching those of UVSphere.
+func sphereTexCoord(p geom.Vec3) geom.Vec2 {
+	u := math.Atan2(float64(p.Z), float64(p.X)) / (2 * math.Pi)
+	if u < 0 {
+		u++
+	}
+	v := math.Acos(math.Max(-1, math.Min(float64(p.Y), 1))) / math.Pi
+	return geom.Vec2{X: float32(u), Y: float32(v)}
+}
+
+func min3(a, b, c float32) float32 {
+	return float32(math.Min(float64(a), math.Min(float64(b), float64(c))))
+}
+
+func max3(a, b, c float32) float32 {
+	return float32(math.Max(float64(a), math.Max(float64(b), float64(c))))
+}
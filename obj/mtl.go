@@ -0,0 +1,137 @@
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	// Texture maps are usually PNG or JPEG images
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Material describes the surface of a mesh, read from an MTL file.
+type Material struct {
+	Name string
+	// The diffuse color, Kd. Most exporters write it as an sRGB color, so it is
+	// treated as one.
+	Diffuse geom.Vec3
+	// The path of the diffuse texture map, map_Kd. It is relative to the MTL file
+	// when read by ReadMTL, and resolved by Load.
+	DiffuseMap string
+	// The opacity, from d or 1 minus Tr.
+	Opacity float32
+}
+
+// ReadMTL reads the materials in an MTL file.
+func ReadMTL(r io.Reader) ([]*Material, error) {
+	materials := make([]*Material, 0)
+	var current *Material
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: newmtl needs a material name", lineNum)
+			}
+			current = &Material{
+				Name:    strings.Join(fields[1:], " "),
+				Diffuse: geom.Vec3{X: 1, Y: 1, Z: 1},
+				Opacity: 1,
+			}
+			materials = append(materials, current)
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %s before newmtl", lineNum, fields[0])
+		}
+
+		switch fields[0] {
+		case "Kd":
+			v, err := parseFloats(fields[1:], 3, 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: Kd: %v", lineNum, err)
+			}
+			current.Diffuse = geom.Vec3{X: v[0], Y: v[1], Z: v[2]}
+		case "map_Kd":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: map_Kd needs a file name", lineNum)
+			}
+			// Options such as -s come before the file name, which is last
+			current.DiffuseMap = fields[len(fields)-1]
+		case "d":
+			v, err := parseFloats(fields[1:], 1, 1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: d: %v", lineNum, err)
+			}
+			current.Opacity = v[0]
+		case "Tr":
+			v, err := parseFloats(fields[1:], 1, 1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: Tr: %v", lineNum, err)
+			}
+			current.Opacity = 1 - v[0]
+		default:
+			// Specular, ambient and other properties are not supported
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// Texture returns a texture for the material. It is the diffuse texture map if
+// there is one, which is read from disk every time, tinted by the diffuse
+// color, and otherwise the diffuse color alone.
+func (m *Material) Texture() (canvas.Texture, error) {
+	diffuse := color.RGBA{
+		R: toByte(m.Diffuse.X),
+		G: toByte(m.Diffuse.Y),
+		B: toByte(m.Diffuse.Z),
+		A: 255,
+	}
+	if m.DiffuseMap == "" {
+		return &canvas.SolidColor{Color: diffuse}, nil
+	}
+
+	f, err := os.Open(m.DiffuseMap)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", m.DiffuseMap, err)
+	}
+	return &canvas.Tinted{
+		Texture: &canvas.ImageTextureWrapped{Img: img, Scale: 1},
+		Factor:  canvas.DecodeSRGB(diffuse),
+	}, nil
+}
+
+func toByte(x float32) uint8 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 255
+	}
+	return uint8(x*255 + 0.5)
+}
@@ -0,0 +1,93 @@
+package obj
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+func TestReadMTL(t *testing.T) {
+	file := `# Two materials
+newmtl plain
+Ka 0.1 0.1 0.1
+
+newmtl glass pane
+Kd 0.5 0.25 1
+d 0.5
+Tr 0.75
+map_Kd -o 0 0 glass.png
+`
+	materials, err := ReadMTL(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ReadMTL() error = %v", err)
+	}
+	want := []*Material{
+		{Name: "plain", Diffuse: geom.Vec3{X: 1, Y: 1, Z: 1}, Opacity: 1},
+		{Name: "glass pane", Diffuse: geom.Vec3{X: 0.5, Y: 0.25, Z: 1}, DiffuseMap: "glass.png", Opacity: 0.25},
+	}
+	if !reflect.DeepEqual(materials, want) {
+		t.Errorf("ReadMTL() = %v, want %v", materials, want)
+	}
+}
+
+func TestReadMTLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"property before newmtl", "Kd 1 1 1\n", "line 1: Kd before newmtl"},
+		{"newmtl without name", "newmtl\n", "line 1: newmtl needs a material name"},
+		{"short Kd", "newmtl a\nKd 1 1\n", "line 2: Kd: expected 3 values, found 2"},
+		{"map_Kd without file", "newmtl a\nmap_Kd\n", "line 2: map_Kd needs a file name"},
+	}
+	for _, tt := range tests {
+		_, err := ReadMTL(strings.NewReader(tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReadMTL() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMaterialTexture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "white.png")
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	red := geom.Vec3{X: 1}
+	plain, err := (&Material{Diffuse: red}).Texture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&canvas.SolidColor{Color: color.RGBA{R: 255, A: 255}}); !reflect.DeepEqual(plain, want) {
+		t.Errorf("Texture() without a map = %v, want %v", plain, want)
+	}
+
+	// The diffuse color tints the texture map
+	mapped, err := (&Material{Diffuse: red, DiffuseMap: path}).Texture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tinted, ok := mapped.(*canvas.Tinted)
+	if !ok || tinted.Factor != red {
+		t.Errorf("Texture() with a map = %v, want the map tinted by %v", mapped, red)
+	}
+}
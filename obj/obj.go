@@ -0,0 +1,337 @@
+// Package obj reads shapes from Wavefront OBJ files, and their materials from
+// MTL files.
+//
+// OBJ files use a right-handed coordinate system, so shapes are mirrored along
+// the Z-axis to fit the left-handed system used by the rasterizer, and texture
+// coordinates are flipped so that V increases down the image.
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Model is the shapes and materials read from an OBJ file.
+type Model struct {
+	Meshes    []*Mesh
+	Materials []*Material
+	// The MTL files named by the OBJ file, relative to it.
+	MaterialLibraries []string
+}
+
+// Mesh is the faces of an OBJ file that share an object, group and material.
+type Mesh struct {
+	Object       string
+	Group        string
+	MaterialName string
+	// The material named by MaterialName, if it was found.
+	Material *Material
+	// The faces of the mesh, triangulated. Its Material is the index of the
+	// mesh's material in the model's Materials, or -1 if it was not found.
+	Triangles *canvas.IndexedTriangleList
+}
+
+// Material returns the material with the given name, or nil if there is none.
+func (m *Model) Material(name string) *Material {
+	for _, mat := range m.Materials {
+		if mat.Name == name {
+			return mat
+		}
+	}
+	return nil
+}
+
+// Load reads the OBJ file at path, along with the MTL files that it names.
+// Paths of texture maps are made relative to the working directory.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	model, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for _, lib := range model.MaterialLibraries {
+		libPath := filepath.Join(dir, lib)
+		materials, err := loadMTL(libPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, mat := range materials {
+			if mat.DiffuseMap != "" {
+				mat.DiffuseMap = filepath.Join(filepath.Dir(libPath), mat.DiffuseMap)
+			}
+		}
+		model.Materials = append(model.Materials, materials...)
+	}
+
+	model.resolveMaterials()
+	return model, nil
+}
+
+func loadMTL(path string) ([]*Material, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	materials, err := ReadMTL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return materials, nil
+}
+
+// Read reads a model in the OBJ format. Material libraries are not read, so
+// meshes only have the names of their materials, and no material index.
+func Read(r io.Reader) (*Model, error) {
+	p := &parser{model: &Model{}}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := p.parseLine(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p.finishMesh()
+	return p.model, nil
+}
+
+// parser holds the state of an OBJ file as it is read.
+type parser struct {
+	model     *Model
+	positions []geom.Vec3
+	texCoords []geom.Vec2
+	normals   []geom.Vec3
+
+	// The names that apply to the faces that follow
+	object, group, material string
+
+	// The mesh being built, and its vertex for each distinct combination of
+	// position, texture coordinate and normal
+	mesh                     *canvas.IndexedTriangleList
+	vertices                 map[[3]int]int
+	hasTexCoords, hasNormals bool
+}
+
+func (p *parser) parseLine(fields []string) error {
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(fields[1:], 3, 4)
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, geom.Vec3{X: v[0], Y: v[1], Z: -v[2]})
+	case "vt":
+		v, err := parseFloats(fields[1:], 1, 3)
+		if err != nil {
+			return err
+		}
+		texCoord := geom.Vec2{X: v[0], Y: 1}
+		if len(v) > 1 {
+			texCoord.Y = 1 - v[1]
+		}
+		p.texCoords = append(p.texCoords, texCoord)
+	case "vn":
+		v, err := parseFloats(fields[1:], 3, 3)
+		if err != nil {
+			return err
+		}
+		p.normals = append(p.normals, geom.Vec3{X: v[0], Y: v[1], Z: -v[2]})
+	case "f":
+		return p.parseFace(fields[1:])
+	case "o":
+		p.finishMesh()
+		p.object = strings.Join(fields[1:], " ")
+	case "g":
+		p.finishMesh()
+		p.group = strings.Join(fields[1:], " ")
+	case "usemtl":
+		if len(fields) < 2 {
+			return fmt.Errorf("usemtl needs a material name")
+		}
+		p.finishMesh()
+		p.material = strings.Join(fields[1:], " ")
+	case "mtllib":
+		if len(fields) < 2 {
+			return fmt.Errorf("mtllib needs a file name")
+		}
+		p.model.MaterialLibraries = append(p.model.MaterialLibraries, fields[1:]...)
+	default:
+		// Smoothing groups, lines, free-form geometry and other statements are
+		// not supported, and are ignored
+	}
+	return nil
+}
+
+// parseFace adds a polygon to the current mesh, split into a fan of triangles.
+func (p *parser) parseFace(fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("face needs at least 3 vertices, found %d", len(fields))
+	}
+
+	indices := make([]int, len(fields))
+	for i, field := range fields {
+		index, err := p.faceVertex(field)
+		if err != nil {
+			return err
+		}
+		indices[i] = index
+	}
+
+	// Mirroring the Z-axis reverses the winding of the polygon, which we undo by
+	// reversing the order of its vertices
+	for i := 1; i+1 < len(indices); i++ {
+		p.mesh.Indices = append(p.mesh.Indices, indices[0], indices[i+1], indices[i])
+	}
+	return nil
+}
+
+// faceVertex returns the index in the current mesh of the vertex described by
+// a face's v, v/vt, v//vn or v/vt/vn reference, adding it if it is new.
+func (p *parser) faceVertex(field string) (int, error) {
+	parts := strings.Split(field, "/")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid face vertex %q", field)
+	}
+
+	// Each part is an index into its list of attributes, or -1 if it is absent
+	key := [3]int{-1, -1, -1}
+	counts := [3]int{len(p.positions), len(p.texCoords), len(p.normals)}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return 0, fmt.Errorf("face vertex %q has no position", field)
+			}
+			continue
+		}
+		index, err := resolveIndex(part, counts[i])
+		if err != nil {
+			return 0, fmt.Errorf("face vertex %q: %v", field, err)
+		}
+		key[i] = index
+	}
+
+	if p.mesh == nil {
+		p.mesh = &canvas.IndexedTriangleList{}
+		p.vertices = make(map[[3]int]int)
+		p.hasTexCoords, p.hasNormals = false, false
+	}
+	if index, ok := p.vertices[key]; ok {
+		return index, nil
+	}
+
+	var texCoord geom.Vec2
+	if key[1] >= 0 {
+		texCoord = p.texCoords[key[1]]
+		p.hasTexCoords = true
+	}
+	var normal geom.Vec3
+	if key[2] >= 0 {
+		normal = p.normals[key[2]]
+		p.hasNormals = true
+	}
+
+	index := len(p.mesh.Vertices)
+	p.mesh.Vertices = append(p.mesh.Vertices, p.positions[key[0]])
+	p.mesh.TexCoords = append(p.mesh.TexCoords, texCoord)
+	p.mesh.Normals = append(p.mesh.Normals, normal)
+	p.vertices[key] = index
+	return index, nil
+}
+
+// finishMesh adds the current mesh to the model, if it has any faces.
+func (p *parser) finishMesh() {
+	if p.mesh == nil {
+		return
+	}
+	if !p.hasTexCoords {
+		p.mesh.TexCoords = nil
+	}
+	if !p.hasNormals {
+		p.mesh.Normals = nil
+	}
+	p.mesh.Material = -1
+	p.model.Meshes = append(p.model.Meshes, &Mesh{
+		Object:       p.object,
+		Group:        p.group,
+		MaterialName: p.material,
+		Triangles:    p.mesh,
+	})
+	p.mesh = nil
+	p.vertices = nil
+}
+
+// resolveMaterials links each mesh to its material.
+func (m *Model) resolveMaterials() {
+	for _, mesh := range m.Meshes {
+		for i, mat := range m.Materials {
+			if mat.Name == mesh.MaterialName {
+				mesh.Material = mat
+				mesh.Triangles.Material = i
+				break
+			}
+		}
+	}
+}
+
+// resolveIndex converts a 1-based index, or a negative index counting back from
+// the most recent element, into a 0-based index.
+func resolveIndex(s string, count int) (int, error) {
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", s)
+	}
+	switch {
+	case index > 0 && index <= count:
+		return index - 1, nil
+	case index < 0 && -index <= count:
+		return count + index, nil
+	default:
+		return 0, fmt.Errorf("index %d out of range, only %d defined", index, count)
+	}
+}
+
+// parseFloats parses between min and max numbers.
+func parseFloats(fields []string, min, max int) ([]float32, error) {
+	if len(fields) < min || len(fields) > max {
+		if min == max {
+			return nil, fmt.Errorf("expected %d values, found %d", min, len(fields))
+		}
+		return nil, fmt.Errorf("expected %d to %d values, found %d", min, max, len(fields))
+	}
+	values := make([]float32, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		values[i] = float32(value)
+	}
+	return values, nil
+}
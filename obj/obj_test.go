@@ -0,0 +1,134 @@
+package obj
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	geom "rasterizer/geometry"
+)
+
+func TestRead(t *testing.T) {
+	file := `# A quad with texture coordinates, referring back with negative indices
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+f -4/-4/1 -3/-3/1 -2/-2/1 -1/-1/1
+f 1/1/1 3/3/1 4/4/1
+`
+	model, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(model.Meshes) != 1 {
+		t.Fatalf("read %d meshes, want 1", len(model.Meshes))
+	}
+	list := model.Meshes[0].Triangles
+
+	// Shared corners become the same vertex
+	if want := []geom.Vec3{{Z: -1}, {X: 1, Z: -1}, {X: 1, Y: 1, Z: -1}, {Y: 1, Z: -1}}; !reflect.DeepEqual(list.Vertices, want) {
+		t.Errorf("vertices = %v, want %v", list.Vertices, want)
+	}
+	// V increases down the image
+	if want := []geom.Vec2{{Y: 1}, {X: 1, Y: 1}, {X: 1}, {}}; !reflect.DeepEqual(list.TexCoords, want) {
+		t.Errorf("texture coordinates = %v, want %v", list.TexCoords, want)
+	}
+	if want := []geom.Vec3{{Z: -1}, {Z: -1}, {Z: -1}, {Z: -1}}; !reflect.DeepEqual(list.Normals, want) {
+		t.Errorf("normals = %v, want %v", list.Normals, want)
+	}
+	// The quad is split into a fan, and every triangle's winding is reversed
+	// to undo the mirror
+	if want := []int{0, 2, 1, 0, 3, 2, 0, 3, 2}; !reflect.DeepEqual(list.Indices, want) {
+		t.Errorf("indices = %v, want %v", list.Indices, want)
+	}
+	if list.Material != -1 {
+		t.Errorf("material index = %d, want -1", list.Material)
+	}
+}
+
+func TestReadDistinctVertices(t *testing.T) {
+	// The same position with different normals is two vertices
+	file := "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nvn 0 0 -1\nf 1//1 2//1 3//1\nf 1//2 3//2 2//2\n"
+	model, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	list := model.Meshes[0].Triangles
+	if len(list.Vertices) != 6 {
+		t.Errorf("read %d vertices, want 6", len(list.Vertices))
+	}
+	if list.TexCoords != nil {
+		t.Errorf("texture coordinates = %v, want none", list.TexCoords)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"short vertex", "v 1 2\n", "line 1: expected 3 to 4 values, found 2"},
+		{"bad number", "v 1 2 x\n", `line 1: invalid number "x"`},
+		{"index out of range", "v 0 0 0\n\nf 1 2 3\n", "line 3: face vertex \"2\": index 2 out of range, only 1 defined"},
+		{"negative index out of range", "v 0 0 0\nf -1 -1 -2\n", "line 2: face vertex \"-2\": index -2 out of range"},
+		{"missing position", "v 0 0 0\nf 1 /1 1\n", `line 2: face vertex "/1" has no position`},
+		{"too few corners", "v 0 0 0\nf 1 1\n", "line 2: face needs at least 3 vertices, found 2"},
+		{"usemtl without name", "usemtl\n", "line 1: usemtl needs a material name"},
+	}
+	for _, tt := range tests {
+		_, err := Read(strings.NewReader(tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Read() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadMaterials(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"model.obj": "mtllib model.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\n" +
+			"usemtl red\nf 1 2 3\nusemtl blue\nf 1 2 3\nusemtl missing\nf 1 2 3\n",
+		"model.mtl": "newmtl blue\nKd 0 0 1\nnewmtl red\nKd 1 0 0\nmap_Kd -s 2 2 textures/red.png\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	model, err := Load(filepath.Join(dir, "model.obj"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		material string
+		index    int
+	}{
+		{"red", 1},
+		{"blue", 0},
+		{"missing", -1},
+	}
+	for i, tt := range tests {
+		mesh := model.Meshes[i]
+		if mesh.MaterialName != tt.material || mesh.Triangles.Material != tt.index {
+			t.Errorf("mesh %d: material %q with index %d, want %q with index %d",
+				i, mesh.MaterialName, mesh.Triangles.Material, tt.material, tt.index)
+		}
+		if (mesh.Material != nil) != (tt.index >= 0) {
+			t.Errorf("mesh %d: Material = %v", i, mesh.Material)
+		}
+	}
+	// Texture maps are found relative to the MTL file
+	if want := filepath.Join(dir, "textures", "red.png"); model.Materials[1].DiffuseMap != want {
+		t.Errorf("texture map = %q, want %q", model.Materials[1].DiffuseMap, want)
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"image"
 	"image/color"
+	"math"
+	"sort"
+
 	"rasterizer/canvas"
 	geom "rasterizer/geometry"
+	"rasterizer/scene"
 )
 
 // VertexShader is a shader in the pipeline that processes individual vertices.
@@ -11,40 +16,446 @@ type VertexShader interface {
 	Process(v geom.Vec3) geom.Vec3
 }
 
-// GeometryShader is a shader in the pipeline that processes assembled triangles.
+// GeometryShader is a shader in the pipeline that processes assembled primitives.
+// It is given one vertex for points, two for lines and three for triangles.
 type GeometryShader interface {
 	Process(vertices []geom.Vec3, index int) []canvas.TexVertex
 }
 
+// BoundsTransformer is implemented by vertex shaders that can transform a
+// bounding sphere the same way as they transform vertices. The pipeline uses it
+// to skip shapes that are entirely outside the view, without shading any of
+// their vertices.
+type BoundsTransformer interface {
+	TransformSphere(s geom.Sphere) geom.Sphere
+}
+
+// NormalTransformer is implemented by vertex shaders that can transform surface
+// normals the same way as they transform vertices. Shapes with their own normals
+// only use them if the vertex shader implements it; otherwise the normal of each
+// face is used.
+type NormalTransformer interface {
+	TransformNormal(n geom.Vec3) geom.Vec3
+}
+
+// Primitives closer to the viewer than the near plane are clipped.
+const nearPlane = 0.1
+
 // Pipeline encapsulates the process of rendering a 3D scene to the screen.
 type Pipeline struct {
-	canv           canvas.Canvas
+	canv canvas.Canvas
+	// If set, surfaces are written to the GBuffer instead of the canvas, to be
+	// lit later by a separate lighting pass.
+	gbuffer *canvas.GBuffer
+	// The area of the canvas that the scene is mapped to. If empty, the whole
+	// canvas is used.
+	viewport image.Rectangle
+	// The size in pixels of the sprites drawn for points.
+	pointSize float32
+	// Transforms shapes from their own space to the scene. If nil, shapes are
+	// already in the scene's space.
+	model *geom.Mat4
+	// Maps the viewer's space onto the viewport. If nil, a perspective
+	// projection with a field of view of 90 degrees is used.
+	projection geom.Projection
+	// Transforms the scene to the viewer's space. If nil, the scene is already
+	// in the viewer's space.
 	vertexShader   VertexShader
 	geometryShader GeometryShader
+	// Transparent primitives waiting to be drawn by Flush.
+	transparent []transparentPrimitive
+	// If set, transparent primitives are drawn using weighted blended
+	// order-independent transparency instead of being sorted.
+	orderIndependent bool
 }
 
-// Draw renders the given triangles onto the screen.
+// Draw renders the given primitives onto the screen.
 func (p *Pipeline) Draw(triangleList *canvas.IndexedTriangleList, tex canvas.Texture) {
-	vertices := make([]geom.Vec3, 0, len(triangleList.Vertices))
-	for _, vertex := range triangleList.Vertices {
-		vertices = append(vertices, p.vertexShader.Process(vertex))
+	if !p.inView(triangleList) {
+		return
+	}
+
+	vertices := p.processVertices(triangleList.Vertices)
+	normals := p.processNormals(triangleList.Normals)
+
+	primitives, primitiveIndices := assemblePrimitives(vertices, triangleList, p.backFacing(triangleList))
+
+	for i := 0; i < len(primitives); i++ {
+		processed := p.shadePrimitive(triangleList, vertices, normals, primitives[i], primitiveIndices[i])
+		p.drawPrimitive(processed, tex, triangleList.Material)
 	}
+}
+
+// Returns false if the shape is certainly outside the view frustum. Shapes are
+// only tested if the vertex shader can transform their bounds.
+func (p *Pipeline) inView(triangleList *canvas.IndexedTriangleList) bool {
+	bounds, ok := p.bounds(triangleList)
+	return !ok || p.frustum().IntersectsSphere(bounds)
+}
+
+// Returns a sphere in the viewer's space containing the shape, or false if the
+// vertex shader cannot transform it.
+func (p *Pipeline) bounds(triangleList *canvas.IndexedTriangleList) (geom.Sphere, bool) {
+	bounds := triangleList.BoundingSphere()
+	if p.model != nil {
+		bounds = bounds.Transform(p.model)
+	}
+	if p.vertexShader == nil {
+		return bounds, true
+	}
+	transformer, ok := p.vertexShader.(BoundsTransformer)
+	if !ok {
+		return geom.Sphere{}, false
+	}
+	return transformer.TransformSphere(bounds), true
+}
 
-	triangles, triangleIndices := assembleTriangles(vertices, triangleList.Indices)
+// Returns the volume of the scene that is projected onto the viewport.
+func (p *Pipeline) frustum() geom.Frustum {
+	return geom.FrustumFromMatrix(p.currentProjection().Matrix(), nearPlane)
+}
 
-	processedTriangles := make([][]canvas.TexVertex, 0, len(triangles))
-	for i := 0; i < len(triangles); i++ {
-		processedTriangles = append(processedTriangles, p.geometryShader.Process(triangles[i][:], triangleIndices[i]))
+// Returns the projection used to map the viewer's space onto the viewport.
+func (p *Pipeline) currentProjection() geom.Projection {
+	if p.projection == nil {
+		return geom.Perspective{FOV: math.Pi / 2, Aspect: 1}
 	}
+	return p.projection
+}
 
-	for _, tri := range processedTriangles {
-		p.canv.FillTriangle(
-			p.transformPerspective(tri[0]),
-			p.transformPerspective(tri[1]),
-			p.transformPerspective(tri[2]),
-			tex,
-		)
+// DrawScene renders the shapes of the node and its descendants, each with its
+// own world transform.
+func (p *Pipeline) DrawScene(root *scene.Node) {
+	p.walkScene(root, func(node *scene.Node) {
+		p.Draw(node.Mesh, node.Texture)
+	})
+}
+
+// Calls fn for every node with a shape, with the model matrix set to the node's
+// world transform.
+func (p *Pipeline) walkScene(root *scene.Node, fn func(node *scene.Node)) {
+	model := p.model
+	root.Walk(func(node *scene.Node, world *geom.Mat4) {
+		if node.Mesh == nil {
+			return
+		}
+		p.model = world
+		fn(node)
+	})
+	p.model = model
+}
+
+// Runs the model transform and the vertex shader on each of the vertices.
+func (p *Pipeline) processVertices(vertices []geom.Vec3) []geom.Vec3 {
+	processed := make([]geom.Vec3, 0, len(vertices))
+	for _, vertex := range vertices {
+		if p.model != nil {
+			vertex = p.model.TransformPoint(vertex)
+		}
+		if p.vertexShader != nil {
+			vertex = p.vertexShader.Process(vertex)
+		}
+		processed = append(processed, vertex)
 	}
+	return processed
+}
+
+// Transforms the normals to the viewer's space, like processVertices does for
+// vertices. Returns nil if the shape has no normals, or if the vertex shader
+// cannot transform them.
+func (p *Pipeline) processNormals(normals []geom.Vec3) []geom.Vec3 {
+	if normals == nil {
+		return nil
+	}
+	transformer, ok := p.vertexShader.(NormalTransformer)
+	if p.vertexShader != nil && !ok {
+		return nil
+	}
+
+	// Normals stay perpendicular to surfaces when transformed by the inverse
+	// transpose of the model's linear part
+	var normalMatrix *geom.Mat3
+	if p.model != nil {
+		inverse, invertible := p.model.Mat3().Inverse()
+		if !invertible {
+			return nil
+		}
+		normalMatrix = inverse.Transpose()
+	}
+
+	processed := make([]geom.Vec3, 0, len(normals))
+	for _, normal := range normals {
+		if normalMatrix != nil {
+			normal = normalMatrix.VecMul(normal)
+		}
+		if transformer != nil {
+			normal = transformer.TransformNormal(normal)
+		}
+		processed = append(processed, normal.Normalize())
+	}
+	return processed
+}
+
+// Returns the vertices of the primitive with their surface attributes. Shapes
+// with their own texture coordinates use them, and otherwise the geometry shader
+// provides them. Normals and colors are only set if the shape has them.
+func (p *Pipeline) shadePrimitive(
+	triangleList *canvas.IndexedTriangleList,
+	vertices, normals []geom.Vec3,
+	prim []int,
+	index int) []canvas.TexVertex {
+	var processed []canvas.TexVertex
+	if triangleList.TexCoords == nil && p.geometryShader != nil {
+		processed = p.geometryShader.Process(primitivePositions(vertices, prim), index)
+	} else {
+		processed = make([]canvas.TexVertex, len(prim))
+		for i, idx := range prim {
+			processed[i].Pos = vertices[idx]
+			if triangleList.TexCoords != nil {
+				processed[i].TexPos = triangleList.TexCoords[idx]
+			}
+		}
+	}
+
+	if normals != nil {
+		for i, idx := range prim {
+			processed[i].Normal = normals[idx]
+		}
+	}
+	if triangleList.Colors != nil {
+		for i, idx := range prim {
+			processed[i].Color = triangleList.Colors[idx]
+		}
+	}
+	return processed
+}
+
+// DrawShadow renders the depth of the given triangles, as seen from the light,
+// into the shadow map. Points and lines do not cast shadows.
+func (p *Pipeline) DrawShadow(triangleList *canvas.IndexedTriangleList, shadowMap *canvas.ShadowMap) {
+	vertices := p.processVertices(triangleList.Vertices)
+
+	// Faces pointing away from the viewer can still face the light, so we do not
+	// cull them.
+	primitives, _ := assemblePrimitives(vertices, triangleList, nil)
+	for _, prim := range primitives {
+		if len(prim) == 3 {
+			shadowMap.DrawTriangle(vertices[prim[0]], vertices[prim[1]], vertices[prim[2]])
+		}
+	}
+}
+
+// DrawTransparent queues the given primitives to be drawn with the given
+// opacity when Flush is called. Transparent primitives are always drawn to the
+// canvas, even if a GBuffer is set.
+func (p *Pipeline) DrawTransparent(triangleList *canvas.IndexedTriangleList, tex canvas.Texture, opacity float32) {
+	if !p.inView(triangleList) {
+		return
+	}
+
+	vertices := p.processVertices(triangleList.Vertices)
+	normals := p.processNormals(triangleList.Normals)
+
+	primitives, primitiveIndices := assemblePrimitives(vertices, triangleList, p.backFacing(triangleList))
+
+	for i := 0; i < len(primitives); i++ {
+		processed := p.shadePrimitive(triangleList, vertices, normals, primitives[i], primitiveIndices[i])
+		setSurface(processed)
+		for _, prim := range p.clipAndProject(processed) {
+			p.transparent = append(p.transparent, transparentPrimitive{
+				vertices: prim,
+				tex:      tex,
+				opacity:  opacity,
+			})
+		}
+	}
+}
+
+// Flush draws the queued transparent primitives on top of everything drawn so
+// far. Unless order-independent transparency is enabled, they are sorted and
+// drawn from back to front.
+func (p *Pipeline) Flush() {
+	if p.orderIndependent {
+		p.canv.SetTransparencyMode(canvas.WeightedBlended)
+	} else {
+		p.canv.SetTransparencyMode(canvas.SortedBlending)
+		perspective := !p.currentProjection().Affine()
+		sort.SliceStable(p.transparent, func(i, j int) bool {
+			return p.transparent[i].depth(perspective) > p.transparent[j].depth(perspective)
+		})
+	}
+
+	p.canv.SetPerspectiveCorrect(!p.currentProjection().Affine())
+	for _, prim := range p.transparent {
+		p.canv.SetOpacity(prim.opacity)
+		p.fillCanvas(prim.vertices, prim.tex)
+	}
+	p.canv.SetOpacity(1)
+	p.canv.ResolveTransparency()
+
+	p.transparent = p.transparent[:0]
+}
+
+// transparentPrimitive is a projected primitive waiting to be drawn by Flush.
+type transparentPrimitive struct {
+	vertices []canvas.TexVertex
+	tex      canvas.Texture
+	opacity  float32
+}
+
+// Returns the average depth of the primitive's vertices, which were projected
+// with or without perspective.
+func (t *transparentPrimitive) depth(perspective bool) float32 {
+	var total float32
+	for _, v := range t.vertices {
+		// With perspective, the Z-component of projected vertices holds 1/Z
+		if perspective {
+			total += 1 / v.Pos.Z
+		} else {
+			total += v.Pos.Z
+		}
+	}
+	return total / float32(len(t.vertices))
+}
+
+// Clips, projects and rasterizes a single point, line or triangle.
+func (p *Pipeline) drawPrimitive(prim []canvas.TexVertex, tex canvas.Texture, material int) {
+	setSurface(prim)
+
+	perspective := !p.currentProjection().Affine()
+	if p.gbuffer != nil {
+		p.gbuffer.SetPerspectiveCorrect(perspective)
+	} else {
+		p.canv.SetPerspectiveCorrect(perspective)
+	}
+
+	for _, projected := range p.clipAndProject(prim) {
+		if p.gbuffer != nil {
+			fillGBuffer(p.gbuffer, projected, tex, material, p.pointSize)
+		} else {
+			p.fillCanvas(projected, tex)
+		}
+	}
+}
+
+// Clips the primitive against the near plane and projects it onto the canvas.
+// Clipping a triangle can produce two triangles.
+func (p *Pipeline) clipAndProject(prim []canvas.TexVertex) [][]canvas.TexVertex {
+	projected := make([][]canvas.TexVertex, 0, 1)
+
+	switch len(prim) {
+	case 1:
+		if prim[0].Pos.Z >= nearPlane {
+			projected = append(projected, []canvas.TexVertex{p.transformProjection(prim[0])})
+		}
+	case 2:
+		if v0, v1, ok := clipLine(prim[0], prim[1]); ok {
+			projected = append(projected, []canvas.TexVertex{
+				p.transformProjection(v0),
+				p.transformProjection(v1),
+			})
+		}
+	case 3:
+		polygon := clipPolygon(prim)
+		for i := 1; i+1 < len(polygon); i++ {
+			projected = append(projected, []canvas.TexVertex{
+				p.transformProjection(polygon[0]),
+				p.transformProjection(polygon[i]),
+				p.transformProjection(polygon[i+1]),
+			})
+		}
+	}
+
+	return projected
+}
+
+// Rasterizes a projected primitive onto the canvas.
+func (p *Pipeline) fillCanvas(prim []canvas.TexVertex, tex canvas.Texture) {
+	switch len(prim) {
+	case 1:
+		p.canv.FillPoint(prim[0], p.pointSize, tex)
+	case 2:
+		p.canv.FillLine(prim[0], prim[1], tex)
+	case 3:
+		p.canv.FillTriangle(prim[0], prim[1], prim[2], tex)
+	}
+}
+
+// Rasterizes a projected primitive into the GBuffer.
+func fillGBuffer(g *canvas.GBuffer, prim []canvas.TexVertex, tex canvas.Texture, material int, pointSize float32) {
+	switch len(prim) {
+	case 1:
+		g.FillPoint(prim[0], pointSize, tex, material)
+	case 2:
+		g.FillLine(prim[0], prim[1], tex, material)
+	case 3:
+		g.FillTriangle(prim[0], prim[1], prim[2], tex, material)
+	}
+}
+
+// Records the scene position and normal of each vertex of the primitive, so that
+// they are available to the later shading stages. Unless the vertices already
+// have normals, triangles use their face normal, while points and lines face the
+// viewer.
+func setSurface(prim []canvas.TexVertex) {
+	hasNormals := prim[0].Normal != (geom.Vec3{})
+
+	var normal geom.Vec3
+	if len(prim) == 3 {
+		normal = prim[1].Pos.Sub(prim[0].Pos).Cross(prim[2].Pos.Sub(prim[0].Pos)).Normalize()
+	}
+
+	for i := range prim {
+		prim[i].WorldPos = prim[i].Pos
+		if hasNormals {
+			continue
+		}
+		if len(prim) == 3 {
+			prim[i].Normal = normal
+		} else {
+			prim[i].Normal = prim[i].Pos.Scale(-1).Normalize()
+		}
+	}
+}
+
+// Clips the line against the near plane, and returns false if none of the line
+// is visible.
+func clipLine(v0, v1 canvas.TexVertex) (canvas.TexVertex, canvas.TexVertex, bool) {
+	in0, in1 := v0.Pos.Z >= nearPlane, v1.Pos.Z >= nearPlane
+	switch {
+	case in0 && in1:
+		return v0, v1, true
+	case in0:
+		return v0, clipEdge(v0, v1), true
+	case in1:
+		return clipEdge(v0, v1), v1, true
+	default:
+		return v0, v1, false
+	}
+}
+
+// Clips the convex polygon against the near plane, preserving the order of its
+// vertices. The result has no vertices if the polygon is entirely clipped.
+func clipPolygon(polygon []canvas.TexVertex) []canvas.TexVertex {
+	clipped := make([]canvas.TexVertex, 0, len(polygon)+1)
+	for i, curr := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		currIn, nextIn := curr.Pos.Z >= nearPlane, next.Pos.Z >= nearPlane
+
+		if currIn {
+			clipped = append(clipped, curr)
+		}
+		if currIn != nextIn {
+			clipped = append(clipped, clipEdge(curr, next))
+		}
+	}
+	return clipped
+}
+
+// Returns the point where the edge between v0 and v1 crosses the near plane.
+func clipEdge(v0, v1 canvas.TexVertex) canvas.TexVertex {
+	alpha := (nearPlane - v0.Pos.Z) / (v1.Pos.Z - v0.Pos.Z)
+	return v0.InterpolateTo(v1, alpha)
 }
 
 func colorToVec3(clr color.RGBA) geom.Vec3 {
@@ -55,41 +466,99 @@ func colorToVec3(clr color.RGBA) geom.Vec3 {
 	}
 }
 
-// Build triangles from the indexed list. Also applies backface culling.
-func assembleTriangles(vertices []geom.Vec3, indices []int) ([][3]geom.Vec3, []int) {
-	triangles := make([][3]geom.Vec3, 0)
-	triangleIndices := make([]int, 0)
+// Build primitives from the indexed list, as the indices of their vertices.
+// Also applies backface culling to triangles if facingAway is set.
+func assemblePrimitives(vertices []geom.Vec3, list *canvas.IndexedTriangleList, facingAway func(v0, v1, v2 geom.Vec3) bool) ([][]int, []int) {
+	primitives := make([][]int, 0)
+	primitiveIndices := make([]int, 0)
+	indices := list.Indices
 
-	for i := 0; i < len(indices); i += 3 {
-		idx0, idx1, idx2 := indices[i], indices[i+1], indices[i+2]
-		v0, v1, v2 := vertices[idx0], vertices[idx1], vertices[idx2]
-
-		if triangleFacingAway(v0, v1, v2) {
-			continue
+	switch list.Topology {
+	case canvas.TriangleList, canvas.TriangleStrip, canvas.TriangleFan:
+		// Each triangle's position in the list is its primitive index
+		for i, tri := range list.Triangles() {
+			if facingAway != nil && facingAway(vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]) {
+				continue
+			}
+			primitives = append(primitives, []int{tri[0], tri[1], tri[2]})
+			primitiveIndices = append(primitiveIndices, i)
 		}
+	case canvas.PointList:
+		for i, idx := range indices {
+			primitives = append(primitives, []int{idx})
+			primitiveIndices = append(primitiveIndices, i)
+		}
+	case canvas.LineList:
+		for i := 0; i+1 < len(indices); i += 2 {
+			primitives = append(primitives, []int{indices[i], indices[i+1]})
+			primitiveIndices = append(primitiveIndices, i/2)
+		}
+	case canvas.LineStrip:
+		for i := 0; i+1 < len(indices); i++ {
+			primitives = append(primitives, []int{indices[i], indices[i+1]})
+			primitiveIndices = append(primitiveIndices, i)
+		}
+	}
 
-		triangles = append(triangles, [3]geom.Vec3{v0, v1, v2})
-		triangleIndices = append(triangleIndices, i/3)
+	return primitives, primitiveIndices
+}
+
+// Returns the positions of the vertices of the primitive.
+func primitivePositions(vertices []geom.Vec3, prim []int) []geom.Vec3 {
+	positions := make([]geom.Vec3, len(prim))
+	for i, idx := range prim {
+		positions[i] = vertices[idx]
 	}
+	return positions
+}
 
-	return triangles, triangleIndices
+// Returns a function that tells whether a triangle of the shape in view space
+// faces away from the viewer, for the current projection, or nil if the shape
+// is double-sided and nothing should be culled.
+func (p *Pipeline) backFacing(list *canvas.IndexedTriangleList) func(v0, v1, v2 geom.Vec3) bool {
+	if list.DoubleSided {
+		return nil
+	}
+	projection := p.currentProjection()
+	if !projection.Affine() {
+		// With perspective, the viewer looks at each triangle from the origin
+		return func(v0, v1, v2 geom.Vec3) bool {
+			return triangleFacingAway(v0, v1, v2, v0)
+		}
+	}
+
+	// Otherwise every point is viewed along the same direction, which is
+	// sheared for oblique projections
+	viewDir := geom.UnprojectRay(projection, 0, 0).Dir
+	return func(v0, v1, v2 geom.Vec3) bool {
+		return triangleFacingAway(v0, v1, v2, viewDir)
+	}
 }
 
-func triangleFacingAway(v0, v1, v2 geom.Vec3) bool {
+func triangleFacingAway(v0, v1, v2, viewDir geom.Vec3) bool {
 	// Assumes that the triangle's vertices are defined in clockwise order
 	normal := v1.Sub(v0).Cross(v2.Sub(v0))
 
 	// A positive dot-product indicates that the viewing vector is in the same
 	// direcion as the triangle's normal. This means that we are looking at the
 	// back-face of triangle, which should not be visible.
-	return normal.Dot(v0) > 0
+	return normal.Dot(viewDir) > 0
 }
 
-// Transforms the 3D scene to a 2D scene by applying perspective, that can then
-// be drawn on a canvas.
-func (p *Pipeline) transformPerspective(vertex canvas.TexVertex) canvas.TexVertex {
-	w, h := p.canv.Dimensions()
-	zInv := 1 / vertex.Pos.Z
+// Transforms the 3D scene to a 2D scene by applying the projection, that can
+// then be drawn on a canvas.
+func (p *Pipeline) transformProjection(vertex canvas.TexVertex) canvas.TexVertex {
+	projection := p.currentProjection()
+	pos := projection.Project(vertex.Pos)
+
+	// Affine projections keep the depth in the Z component, and the canvas
+	// interpolates attributes linearly
+	if projection.Affine() {
+		vertex.Pos = vertexToPoint(pos, p.viewportRect())
+		return vertex
+	}
+
+	zInv := 1 / pos.Z
 
 	// We also want to transform the texture coordinates so that perspective is
 	// applied correctly to the texture. We will re-multiply the texture coordinates
@@ -98,18 +567,71 @@ func (p *Pipeline) transformPerspective(vertex canvas.TexVertex) canvas.TexVerte
 
 	// Since the canvas is 2D, we use the Z component to store depth information.
 	// We store 1/Z so that interpolation preserves depth perspective correctly.
-	projected.Pos.Z = zInv
-	return canvas.TexVertex{
-		Pos:    vertexToPoint(projected.Pos, w, h),
-		TexPos: projected.TexPos,
+	pos.Z = zInv
+	projected.Pos = vertexToPoint(pos, p.viewportRect())
+	return projected
+}
+
+// Unproject returns the ray of points in the viewer's space that are drawn at
+// the middle of the pixel at (x, y) on the canvas. It reverses
+// transformProjection.
+func (p *Pipeline) Unproject(x, y int) geom.Ray {
+	viewport := p.viewportRect()
+	halfWidth, halfHeight := float32(viewport.Dx())/2, float32(viewport.Dy())/2
+
+	return geom.UnprojectRay(
+		p.currentProjection(),
+		(float32(x-viewport.Min.X)+0.5)/halfWidth-1,
+		1-(float32(y-viewport.Min.Y)+0.5)/halfHeight,
+	)
+}
+
+// Pick returns the closest node whose shape is drawn at the pixel at (x, y),
+// and the index of its triangle that was hit, as it would be passed to the
+// geometry shader. Only triangles facing the viewer can be picked.
+func (p *Pipeline) Pick(x, y int, root *scene.Node) (picked *scene.Node, triangle int, ok bool) {
+	ray := p.Unproject(x, y)
+	closest := float32(math.Inf(1))
+
+	p.walkScene(root, func(node *scene.Node) {
+		// Skip shapes whose bounds the ray misses, without shading their vertices
+		if bounds, known := p.bounds(node.Mesh); known {
+			if _, hit := ray.IntersectSphere(bounds); !hit && !bounds.Contains(ray.Origin) {
+				return
+			}
+		}
+
+		vertices := p.processVertices(node.Mesh.Vertices)
+		primitives, primitiveIndices := assemblePrimitives(vertices, node.Mesh, p.backFacing(node.Mesh))
+		for i, prim := range primitives {
+			if len(prim) != 3 {
+				continue
+			}
+			// The ray's direction has Z = 1, so t is the depth of the hit point.
+			// Anything closer than the near plane is clipped and not visible.
+			t, _, _, hit := ray.IntersectTriangle(vertices[prim[0]], vertices[prim[1]], vertices[prim[2]])
+			if hit && t >= nearPlane && t < closest {
+				closest, picked, triangle, ok = t, node, primitiveIndices[i], true
+			}
+		}
+	})
+	return picked, triangle, ok
+}
+
+// Returns the area of the canvas that the scene is mapped to.
+func (p *Pipeline) viewportRect() image.Rectangle {
+	if p.viewport.Empty() {
+		w, h := p.canv.Dimensions()
+		return image.Rect(0, 0, w, h)
 	}
+	return p.viewport
 }
 
-func vertexToPoint(v geom.Vec3, width int, height int) geom.Vec3 {
-	halfWidth, halfHeight := float32(width)/2, float32(height)/2
+func vertexToPoint(v geom.Vec3, viewport image.Rectangle) geom.Vec3 {
+	halfWidth, halfHeight := float32(viewport.Dx())/2, float32(viewport.Dy())/2
 	return geom.Vec3{
-		X: (1 + v.X) * halfWidth,
-		Y: (1 - v.Y) * halfHeight,
+		X: float32(viewport.Min.X) + (1+v.X)*halfWidth,
+		Y: float32(viewport.Min.Y) + (1-v.Y)*halfHeight,
 		Z: v.Z,
 	}
 }
@@ -0,0 +1,567 @@
+// Package ply reads and writes shapes in the PLY (Polygon File) format, in its
+// ASCII and binary forms.
+//
+// PLY files use a right-handed coordinate system, so shapes are mirrored along
+// the Z-axis to fit the left-handed system used by the rasterizer, and texture
+// coordinates are flipped so that V increases down the image.
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Format is the way that the elements of a PLY file are stored.
+type Format int
+
+const (
+	// ASCII stores each element as a line of text.
+	ASCII Format = iota
+	// BinaryLittleEndian stores elements as little-endian binary numbers.
+	BinaryLittleEndian
+	// BinaryBigEndian stores elements as big-endian binary numbers.
+	BinaryBigEndian
+)
+
+var formatNames = map[string]Format{
+	"ascii":                ASCII,
+	"binary_little_endian": BinaryLittleEndian,
+	"binary_big_endian":    BinaryBigEndian,
+}
+
+func (f Format) String() string {
+	switch f {
+	case ASCII:
+		return "ascii"
+	case BinaryLittleEndian:
+		return "binary_little_endian"
+	case BinaryBigEndian:
+		return "binary_big_endian"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// Size in bytes of each scalar type, by the names used in headers.
+var typeSizes = map[string]int{
+	"char": 1, "uchar": 1, "short": 2, "ushort": 2,
+	"int": 4, "uint": 4, "float": 4, "double": 8,
+	"int8": 1, "uint8": 1, "int16": 2, "uint16": 2,
+	"int32": 4, "uint32": 4, "float32": 4, "float64": 8,
+}
+
+// maxListLength is the most values that a list property may hold, so that a
+// corrupt count cannot make us allocate gigabytes before the data runs out.
+const maxListLength = 1 << 16
+
+// element is a kind of element declared in a header, eg. vertex or face.
+type element struct {
+	name       string
+	count      int
+	properties []property
+}
+
+// property is a value of each element. List properties hold a number of
+// values, preceded by their count.
+type property struct {
+	name      string
+	typ       string
+	list      bool
+	countType string
+}
+
+// Load reads the PLY file at path.
+func Load(path string) (*canvas.IndexedTriangleList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return list, nil
+}
+
+// Read reads a shape in the PLY format, with the normals, colors and texture
+// coordinates of its vertices if the file has them. Faces are split into fans
+// of triangles, and elements other than vertices and faces are ignored.
+func Read(r io.Reader) (*canvas.IndexedTriangleList, error) {
+	br := bufio.NewReader(r)
+	format, elements, lineNum, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var values valueReader
+	if format == ASCII {
+		values = &asciiReader{scanner: bufio.NewScanner(br), lineNum: lineNum}
+	} else {
+		var order binary.ByteOrder = binary.LittleEndian
+		if format == BinaryBigEndian {
+			order = binary.BigEndian
+		}
+		values = &binaryReader{r: br, order: order}
+	}
+
+	b := &builder{list: &canvas.IndexedTriangleList{Topology: canvas.TriangleList}}
+	for _, elem := range elements {
+		for i := 0; i < elem.count; i++ {
+			if err := b.readElement(elem, values); err != nil {
+				return nil, values.wrap(fmt.Errorf("%s %d: %v", elem.name, i, err))
+			}
+		}
+	}
+	if err := b.finish(); err != nil {
+		return nil, err
+	}
+	return b.list, nil
+}
+
+// readHeader reads the header of a file up to and including end_header, and
+// returns the number of lines that it took.
+func readHeader(r *bufio.Reader) (Format, []*element, int, error) {
+	var format Format
+	var elements []*element
+	hasFormat := false
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return 0, nil, 0, fmt.Errorf("missing end_header")
+			}
+			return 0, nil, 0, err
+		}
+		fields := strings.Fields(line)
+		if lineNum == 1 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return 0, nil, 0, fmt.Errorf("not a PLY file")
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) != 3 {
+				return 0, nil, 0, fmt.Errorf("line %d: format takes a name and a version", lineNum)
+			}
+			f, ok := formatNames[fields[1]]
+			if !ok {
+				return 0, nil, 0, fmt.Errorf("line %d: unknown format %q", lineNum, fields[1])
+			}
+			format, hasFormat = f, true
+		case "element":
+			if len(fields) != 3 {
+				return 0, nil, 0, fmt.Errorf("line %d: element takes a name and a count", lineNum)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return 0, nil, 0, fmt.Errorf("line %d: invalid element count %q", lineNum, fields[2])
+			}
+			elements = append(elements, &element{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return 0, nil, 0, fmt.Errorf("line %d: property before any element", lineNum)
+			}
+			prop, err := parseProperty(fields[1:])
+			if err != nil {
+				return 0, nil, 0, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			elem := elements[len(elements)-1]
+			elem.properties = append(elem.properties, prop)
+		case "comment", "obj_info":
+		case "end_header":
+			if !hasFormat {
+				return 0, nil, 0, fmt.Errorf("missing format")
+			}
+			return format, elements, lineNum, nil
+		default:
+			return 0, nil, 0, fmt.Errorf("line %d: unknown keyword %q", lineNum, fields[0])
+		}
+	}
+}
+
+// parseProperty parses the type and name of a property, eg. "float x" or "list
+// uchar int vertex_indices".
+func parseProperty(fields []string) (property, error) {
+	var prop property
+	if len(fields) > 0 && fields[0] == "list" {
+		if len(fields) != 4 {
+			return prop, fmt.Errorf("list property takes two types and a name")
+		}
+		prop = property{list: true, countType: fields[1], typ: fields[2], name: fields[3]}
+		if _, ok := typeSizes[prop.countType]; !ok {
+			return prop, fmt.Errorf("unknown type %q", prop.countType)
+		}
+	} else {
+		if len(fields) != 2 {
+			return prop, fmt.Errorf("property takes a type and a name")
+		}
+		prop = property{typ: fields[0], name: fields[1]}
+	}
+	if _, ok := typeSizes[prop.typ]; !ok {
+		return prop, fmt.Errorf("unknown type %q", prop.typ)
+	}
+	return prop, nil
+}
+
+// valueReader reads the values of elements, one after the other.
+type valueReader interface {
+	// startElement is called before reading the values of each element.
+	startElement() error
+	// next reads a value of the given type.
+	next(typ string) (float64, error)
+	// wrap adds the position in the file to an error.
+	wrap(err error) error
+}
+
+// asciiReader reads elements from lines of text.
+type asciiReader struct {
+	scanner *bufio.Scanner
+	lineNum int
+	fields  []string
+}
+
+func (a *asciiReader) startElement() error {
+	for a.scanner.Scan() {
+		a.lineNum++
+		a.fields = strings.Fields(a.scanner.Text())
+		if len(a.fields) > 0 {
+			return nil
+		}
+	}
+	if err := a.scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+func (a *asciiReader) next(typ string) (float64, error) {
+	if len(a.fields) == 0 {
+		return 0, fmt.Errorf("too few values")
+	}
+	field := a.fields[0]
+	a.fields = a.fields[1:]
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", field)
+	}
+	return value, nil
+}
+
+func (a *asciiReader) wrap(err error) error {
+	return fmt.Errorf("line %d: %v", a.lineNum, err)
+}
+
+// binaryReader reads elements stored as binary numbers.
+type binaryReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (b *binaryReader) startElement() error {
+	return nil
+}
+
+func (b *binaryReader) next(typ string) (float64, error) {
+	data := b.buf[:typeSizes[typ]]
+	if _, err := io.ReadFull(b.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	switch typ {
+	case "char", "int8":
+		return float64(int8(data[0])), nil
+	case "uchar", "uint8":
+		return float64(data[0]), nil
+	case "short", "int16":
+		return float64(int16(b.order.Uint16(data))), nil
+	case "ushort", "uint16":
+		return float64(b.order.Uint16(data)), nil
+	case "int", "int32":
+		return float64(int32(b.order.Uint32(data))), nil
+	case "uint", "uint32":
+		return float64(b.order.Uint32(data)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(b.order.Uint32(data))), nil
+	default:
+		return math.Float64frombits(b.order.Uint64(data)), nil
+	}
+}
+
+func (b *binaryReader) wrap(err error) error {
+	return err
+}
+
+// builder collects the vertices and faces of a file into a shape.
+type builder struct {
+	list                               *canvas.IndexedTriangleList
+	hasNormals, hasColors, hasTexCoord bool
+}
+
+// readElement reads the values of an element, adding it to the shape if it is
+// a vertex or a face.
+func (b *builder) readElement(elem *element, values valueReader) error {
+	if err := values.startElement(); err != nil {
+		return err
+	}
+
+	var vertex geom.Vec3
+	var normal, clr geom.Vec3
+	var texCoord geom.Vec2
+	for _, prop := range elem.properties {
+		if prop.list {
+			items, err := readList(prop, values)
+			if err != nil {
+				return err
+			}
+			if elem.name == "face" && (prop.name == "vertex_indices" || prop.name == "vertex_index") {
+				if err := b.addFace(items); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		value, err := values.next(prop.typ)
+		if err != nil {
+			return err
+		}
+		if elem.name != "vertex" {
+			continue
+		}
+		x := float32(value)
+		switch prop.name {
+		case "x":
+			vertex.X = x
+		case "y":
+			vertex.Y = x
+		case "z":
+			vertex.Z = -x
+		case "nx":
+			normal.X = x
+			b.hasNormals = true
+		case "ny":
+			normal.Y = x
+		case "nz":
+			normal.Z = -x
+		case "red":
+			clr.X = colorComponent(prop.typ, value)
+			b.hasColors = true
+		case "green":
+			clr.Y = colorComponent(prop.typ, value)
+		case "blue":
+			clr.Z = colorComponent(prop.typ, value)
+		case "s", "u", "texture_u":
+			texCoord.X = x
+			b.hasTexCoord = true
+		case "t", "v", "texture_v":
+			texCoord.Y = 1 - x
+		}
+	}
+
+	if elem.name == "vertex" {
+		b.list.Vertices = append(b.list.Vertices, vertex)
+		b.list.Normals = append(b.list.Normals, normal)
+		b.list.Colors = append(b.list.Colors, clr)
+		b.list.TexCoords = append(b.list.TexCoords, texCoord)
+	}
+	return nil
+}
+
+// readList reads the values of a list property.
+func readList(prop property, values valueReader) ([]int, error) {
+	count, err := values.next(prop.countType)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 || count > maxListLength {
+		return nil, fmt.Errorf("invalid list length %v", count)
+	}
+	items := make([]int, int(count))
+	for i := range items {
+		value, err := values.next(prop.typ)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = int(value)
+	}
+	return items, nil
+}
+
+// colorComponent converts a color component in the file, which is either an
+// integer from 0 to 255 or a number from 0 to 1 in sRGB, to linear RGB.
+func colorComponent(typ string, value float64) float32 {
+	if typ == "float" || typ == "float32" || typ == "double" || typ == "float64" {
+		value = math.Round(value * 0xFF)
+	}
+	value = math.Max(0, math.Min(value, 0xFF))
+	return canvas.DecodeSRGB(color.Gray{Y: uint8(value)}).X
+}
+
+// addFace adds a polygon to the shape, split into a fan of triangles.
+func (b *builder) addFace(indices []int) error {
+	if len(indices) < 3 {
+		return fmt.Errorf("face needs at least 3 vertices, found %d", len(indices))
+	}
+	// Mirroring the Z-axis reverses the winding of the polygon, which we undo by
+	// reversing the order of its vertices
+	for i := 1; i+1 < len(indices); i++ {
+		b.list.Indices = append(b.list.Indices, indices[0], indices[i+1], indices[i])
+	}
+	return nil
+}
+
+// finish checks that the faces refer to vertices of the file, and drops any
+// attributes that the vertices do not have.
+func (b *builder) finish() error {
+	for _, index := range b.list.Indices {
+		if index < 0 || index >= len(b.list.Vertices) {
+			return fmt.Errorf("vertex index %d out of range, only %d defined", index, len(b.list.Vertices))
+		}
+	}
+	if !b.hasNormals {
+		b.list.Normals = nil
+	}
+	if !b.hasColors {
+		b.list.Colors = nil
+	}
+	if !b.hasTexCoord {
+		b.list.TexCoords = nil
+	}
+	return nil
+}
+
+// Write writes the vertices and triangles of a shape in the given format,
+// along with the vertices' normals, colors and texture coordinates if the
+// shape has them. Points and lines are left out.
+func Write(w io.Writer, list *canvas.IndexedTriangleList, format Format) error {
+	triangles := list.Triangles()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\n", format)
+	fmt.Fprintf(bw, "element vertex %d\n", len(list.Vertices))
+	fmt.Fprintf(bw, "property float x\nproperty float y\nproperty float z\n")
+	if list.Normals != nil {
+		fmt.Fprintf(bw, "property float nx\nproperty float ny\nproperty float nz\n")
+	}
+	if list.TexCoords != nil {
+		fmt.Fprintf(bw, "property float s\nproperty float t\n")
+	}
+	if list.Colors != nil {
+		fmt.Fprintf(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	}
+	fmt.Fprintf(bw, "element face %d\n", len(triangles))
+	fmt.Fprintf(bw, "property list uchar int vertex_indices\n")
+	fmt.Fprintf(bw, "end_header\n")
+
+	out := newValueWriter(bw, format)
+	for i, v := range list.Vertices {
+		out.float(v.X)
+		out.float(v.Y)
+		out.float(-v.Z)
+		if list.Normals != nil {
+			n := list.Normals[i]
+			out.float(n.X)
+			out.float(n.Y)
+			out.float(-n.Z)
+		}
+		if list.TexCoords != nil {
+			t := list.TexCoords[i]
+			out.float(t.X)
+			out.float(1 - t.Y)
+		}
+		if list.Colors != nil {
+			clr := canvas.EncodeSRGB(list.Colors[i])
+			out.uchar(clr.R)
+			out.uchar(clr.G)
+			out.uchar(clr.B)
+		}
+		out.endElement()
+	}
+	for _, tri := range triangles {
+		out.uchar(3)
+		for _, index := range [3]int{tri[0], tri[2], tri[1]} {
+			out.int(int32(index))
+		}
+		out.endElement()
+	}
+	return bw.Flush()
+}
+
+// valueWriter writes the values of elements in a given format. Errors are
+// left to the underlying bufio.Writer to report when it is flushed.
+type valueWriter struct {
+	w     *bufio.Writer
+	order binary.ByteOrder
+	// Whether the next ASCII value starts a new element
+	first bool
+}
+
+func newValueWriter(w *bufio.Writer, format Format) *valueWriter {
+	switch format {
+	case BinaryLittleEndian:
+		return &valueWriter{w: w, order: binary.LittleEndian}
+	case BinaryBigEndian:
+		return &valueWriter{w: w, order: binary.BigEndian}
+	default:
+		return &valueWriter{w: w, first: true}
+	}
+}
+
+func (v *valueWriter) float(x float32) {
+	if v.order == nil {
+		v.text(strconv.FormatFloat(float64(x), 'g', -1, 32))
+		return
+	}
+	binary.Write(v.w, v.order, x)
+}
+
+func (v *valueWriter) uchar(x uint8) {
+	if v.order == nil {
+		v.text(strconv.Itoa(int(x)))
+		return
+	}
+	v.w.WriteByte(x)
+}
+
+func (v *valueWriter) int(x int32) {
+	if v.order == nil {
+		v.text(strconv.Itoa(int(x)))
+		return
+	}
+	binary.Write(v.w, v.order, x)
+}
+
+func (v *valueWriter) text(s string) {
+	if !v.first {
+		v.w.WriteByte(' ')
+	}
+	v.w.WriteString(s)
+	v.first = false
+}
+
+func (v *valueWriter) endElement() {
+	if v.order == nil {
+		v.w.WriteByte('\n')
+		v.first = true
+	}
+}
@@ -0,0 +1,60 @@
+package ply
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+func TestRoundTrip(t *testing.T) {
+	// Colors are stored as sRGB bytes, so only those that survive that are used
+	list := &canvas.IndexedTriangleList{
+		Vertices:  []geom.Vec3{{}, {X: 1}, {Y: 1}, {X: 1, Y: 1, Z: 0.5}},
+		Normals:   []geom.Vec3{{Z: -1}, {Z: -1}, {Y: 1}, {X: 0.6, Z: 0.8}},
+		TexCoords: []geom.Vec2{{}, {X: 1}, {Y: 1}, {X: 0.25, Y: 0.75}},
+		Colors:    []geom.Vec3{{}, {X: 1}, {Y: 1, Z: 1}, canvas.DecodeSRGB(canvas.EncodeSRGB(geom.Vec3{X: 0.2, Y: 0.5, Z: 0.8}))},
+		Indices:   []int{0, 1, 2, 3},
+		Topology:  canvas.TriangleStrip,
+	}
+	for _, format := range []Format{ASCII, BinaryLittleEndian, BinaryBigEndian} {
+		var buf bytes.Buffer
+		if err := Write(&buf, list, format); err != nil {
+			t.Fatalf("%s: write: %v", format, err)
+		}
+		got, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("%s: read: %v", format, err)
+		}
+		if len(got.Vertices) != len(list.Vertices) {
+			t.Fatalf("%s: read %d vertices, want %d", format, len(got.Vertices), len(list.Vertices))
+		}
+		for i := range list.Vertices {
+			if got.Vertices[i] != list.Vertices[i] || got.Normals[i] != list.Normals[i] ||
+				got.TexCoords[i] != list.TexCoords[i] || !got.Colors[i].ApproxEqual(list.Colors[i], 1e-6) {
+				t.Errorf("%s: vertex %d = %v %v %v %v, want %v %v %v %v", format, i,
+					got.Vertices[i], got.Normals[i], got.TexCoords[i], got.Colors[i],
+					list.Vertices[i], list.Normals[i], list.TexCoords[i], list.Colors[i])
+			}
+		}
+		gotTris, wantTris := got.Triangles(), list.Triangles()
+		if len(gotTris) != len(wantTris) {
+			t.Fatalf("%s: read %d triangles, want %d", format, len(gotTris), len(wantTris))
+		}
+		for i := range wantTris {
+			if gotTris[i] != wantTris[i] {
+				t.Errorf("%s: triangle %d = %v, want %v", format, i, gotTris[i], wantTris[i])
+			}
+		}
+	}
+}
+
+func TestReadLongList(t *testing.T) {
+	file := "ply\nformat ascii 1.0\nelement vertex 0\nelement face 1\n" +
+		"property list uint int vertex_indices\nend_header\n4000000000 0 1 2\n"
+	if _, err := Read(strings.NewReader(file)); err == nil {
+		t.Errorf("Read() succeeded with a list of 4000000000 values")
+	}
+}
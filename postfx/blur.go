@@ -0,0 +1,103 @@
+package postfx
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// GaussianBlur blurs the canvas with a Gaussian kernel.
+type GaussianBlur struct {
+	// Sigma is the standard deviation of the kernel, in pixels.
+	Sigma float32
+}
+
+// Apply blurs the canvas.
+func (b *GaussianBlur) Apply(c *canvas.Canvas) {
+	blur(snapshot(c), b.Sigma).writeTo(c)
+}
+
+// Bloom makes bright parts of the image bleed light into their surroundings.
+// It works best with an HDR canvas, where colors can exceed 1.
+type Bloom struct {
+	// Only the part of a color's luminance above Threshold blooms. Negative
+	// thresholds are treated as 0.
+	Threshold float32
+	// Intensity scales the bloom before it is added to the image.
+	Intensity float32
+	// Sigma is the standard deviation of the blur used to spread the light.
+	Sigma float32
+}
+
+// Apply adds bloom to the canvas.
+func (b *Bloom) Apply(c *canvas.Canvas) {
+	src := snapshot(c)
+
+	// A threshold of at least 0 keeps black pixels from dividing by zero
+	threshold := float32(math.Max(float64(b.Threshold), 0))
+	bright := newFrame(src.width, src.height)
+	for i, clr := range src.pix {
+		lum := luminance(clr)
+		if lum > threshold {
+			bright.pix[i] = clr.Scale((lum - threshold) / lum)
+		}
+	}
+
+	glow := blur(bright, b.Sigma)
+	for i := range src.pix {
+		src.pix[i] = src.pix[i].Add(glow.pix[i].Scale(b.Intensity))
+	}
+	src.writeTo(c)
+}
+
+// blur returns a copy of f blurred by a Gaussian kernel. The kernel is separable,
+// so we blur horizontally and then vertically.
+func blur(f *frame, sigma float32) *frame {
+	kernel := gaussianKernel(sigma)
+	radius := len(kernel) / 2
+
+	horizontal := newFrame(f.width, f.height)
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			var sum geom.Vec3
+			for k, weight := range kernel {
+				sum = sum.Add(f.at(x+k-radius, y).Scale(weight))
+			}
+			horizontal.set(x, y, sum)
+		}
+	}
+
+	blurred := newFrame(f.width, f.height)
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			var sum geom.Vec3
+			for k, weight := range kernel {
+				sum = sum.Add(horizontal.at(x, y+k-radius).Scale(weight))
+			}
+			blurred.set(x, y, sum)
+		}
+	}
+	return blurred
+}
+
+// gaussianKernel returns normalized one-dimensional weights covering three
+// standard deviations either side of the center.
+func gaussianKernel(sigma float32) []float32 {
+	if sigma <= 0 {
+		return []float32{1}
+	}
+
+	radius := int(math.Ceil(float64(3 * sigma)))
+	kernel := make([]float32, 2*radius+1)
+	var total float32
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = float32(math.Exp(-d * d / float64(2*sigma*sigma)))
+		total += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= total
+	}
+	return kernel
+}
@@ -0,0 +1,64 @@
+package postfx
+
+import (
+	"math"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// hdrCanvas returns a black HDR canvas with a single bright pixel in the middle.
+func hdrCanvas(size int, bright geom.Vec3) *canvas.Canvas {
+	c := canvas.NewCanvas(size, size)
+	c.EnableHDR(nil, 1)
+	c.PutPixelHDR(size/2, size/2, bright)
+	return c
+}
+
+func finite(v geom.Vec3) bool {
+	for _, x := range []float32{v.X, v.Y, v.Z} {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBloom(t *testing.T) {
+	white := geom.Vec3{X: 1, Y: 1, Z: 1}
+	tests := []struct {
+		name      string
+		bloom     Bloom
+		bright    geom.Vec3
+		wantGlow  bool
+		wantBlack bool
+	}{
+		{"above threshold", Bloom{Threshold: 1, Intensity: 1, Sigma: 1}, white.Scale(4), true, false},
+		{"below threshold", Bloom{Threshold: 1, Intensity: 1, Sigma: 1}, white.Scale(0.5), false, true},
+		{"negative threshold", Bloom{Threshold: -1, Intensity: 1, Sigma: 1}, geom.Vec3{}, false, true},
+	}
+	for _, tt := range tests {
+		c := hdrCanvas(9, tt.bright)
+		tt.bloom.Apply(c)
+
+		neighbour := c.ColorAt(5, 4)
+		if got := neighbour.X > 0; got != tt.wantGlow {
+			t.Errorf("%s: neighbour = %v, want glow %v", tt.name, neighbour, tt.wantGlow)
+		}
+		if center := c.ColorAt(4, 4); center.X < tt.bright.X {
+			t.Errorf("%s: center = %v, darker than %v", tt.name, center, tt.bright)
+		}
+		for y := 0; y < 9; y++ {
+			for x := 0; x < 9; x++ {
+				clr := c.ColorAt(x, y)
+				if !finite(clr) {
+					t.Fatalf("%s: pixel (%d, %d) = %v", tt.name, x, y, clr)
+				}
+				if tt.wantBlack && (x != 4 || y != 4) && clr != (geom.Vec3{}) {
+					t.Errorf("%s: pixel (%d, %d) = %v, want black", tt.name, x, y, clr)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package postfx
+
+import (
+	"math"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Vignette darkens the edges of the canvas.
+type Vignette struct {
+	// Strength is how much the corners are darkened, between 0 and 1.
+	Strength float32
+	// Radius is the distance from the center, as a fraction of the distance to
+	// the corners, at which darkening begins. A radius of 1 or more leaves the
+	// canvas unchanged.
+	Radius float32
+}
+
+// Apply darkens the edges of the canvas.
+func (v *Vignette) Apply(c *canvas.Canvas) {
+	if v.Radius >= 1 {
+		return
+	}
+	w, h := c.Dimensions()
+	halfW, halfH := float32(w)/2, float32(h)/2
+	maxDist := float32(math.Hypot(float64(halfW), float64(halfH)))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float32(x)+0.5-halfW, float32(y)+0.5-halfH
+			dist := float32(math.Hypot(float64(dx), float64(dy))) / maxDist
+
+			t := clamp01((dist - v.Radius) / (1 - v.Radius))
+			c.PutPixelHDR(x, y, c.ColorAt(x, y).Scale(1-v.Strength*t*t))
+		}
+	}
+}
+
+// Sharpen increases the contrast between neighbouring pixels.
+type Sharpen struct {
+	// Amount is how strongly the edges are enhanced; 0 has no effect.
+	Amount float32
+}
+
+// Apply sharpens the canvas.
+func (s *Sharpen) Apply(c *canvas.Canvas) {
+	src := snapshot(c)
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			center := src.at(x, y)
+			neighbours := src.at(x-1, y).Add(src.at(x+1, y)).Add(src.at(x, y-1)).Add(src.at(x, y+1))
+			laplacian := center.Scale(4).Sub(neighbours)
+			c.PutPixelHDR(x, y, clampPositive(center.Add(laplacian.Scale(s.Amount))))
+		}
+	}
+}
+
+// EdgeDetect draws edges found using the Sobel operator.
+type EdgeDetect struct {
+	// Pixels whose gradient magnitude exceeds Threshold are considered edges.
+	Threshold float32
+	// Color is the linear RGB color that edges are drawn with.
+	Color geom.Vec3
+	// If UseDepth is set, edges are found in the depth buffer instead of the
+	// luminance of the image, which ignores edges in textures.
+	UseDepth bool
+}
+
+// Apply draws the edges onto the canvas.
+func (e *EdgeDetect) Apply(c *canvas.Canvas) {
+	w, h := c.Dimensions()
+	values := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if e.UseDepth {
+				// Use inverse depth so that empty pixels, at infinite depth,
+				// have a finite value.
+				values[y*w+x] = 1 / c.DepthAt(x, y)
+			} else {
+				values[y*w+x] = luminance(c.ColorAt(x, y))
+			}
+		}
+	}
+
+	at := func(x, y int) float32 {
+		return values[clampInt(y, h)*w+clampInt(x, w)]
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+
+			if gx*gx+gy*gy > e.Threshold*e.Threshold {
+				c.PutPixelHDR(x, y, e.Color)
+			}
+		}
+	}
+}
+
+// ChromaticAberration separates the color channels towards the edges of the
+// canvas, imitating a lens that focuses each wavelength differently.
+type ChromaticAberration struct {
+	// Offset is how far, in pixels, the red and blue channels are shifted at the
+	// corners of the canvas.
+	Offset float32
+}
+
+// Apply separates the color channels of the canvas.
+func (a *ChromaticAberration) Apply(c *canvas.Canvas) {
+	src := snapshot(c)
+	halfW, halfH := float32(src.width)/2, float32(src.height)/2
+	maxDist := float32(math.Hypot(float64(halfW), float64(halfH)))
+
+	for y := 0; y < src.height; y++ {
+		for x := 0; x < src.width; x++ {
+			// Shift along the direction away from the center, by an amount that
+			// grows towards the corners.
+			dx, dy := float32(x)+0.5-halfW, float32(y)+0.5-halfH
+			sx, sy := dx/maxDist*a.Offset, dy/maxDist*a.Offset
+
+			red := src.at(x+round(sx), y+round(sy))
+			blue := src.at(x-round(sx), y-round(sy))
+			c.PutPixelHDR(x, y, geom.Vec3{X: red.X, Y: src.at(x, y).Y, Z: blue.Z})
+		}
+	}
+}
+
+func round(x float32) int {
+	return int(math.Round(float64(x)))
+}
+
+// clampPositive replaces negative components of the color with 0.
+func clampPositive(clr geom.Vec3) geom.Vec3 {
+	return geom.Vec3{
+		X: float32(math.Max(0, float64(clr.X))),
+		Y: float32(math.Max(0, float64(clr.Y))),
+		Z: float32(math.Max(0, float64(clr.Z))),
+	}
+}
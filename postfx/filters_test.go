@@ -0,0 +1,40 @@
+package postfx
+
+import (
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+func TestVignette(t *testing.T) {
+	white := geom.Vec3{X: 1, Y: 1, Z: 1}
+	tests := []struct {
+		name     string
+		vignette Vignette
+		darkened bool
+	}{
+		{"radius 0.5", Vignette{Strength: 1, Radius: 0.5}, true},
+		// Radii of 1 or more used to divide by zero or brighten the corners
+		{"radius 1", Vignette{Strength: 1, Radius: 1}, false},
+		{"radius above 1", Vignette{Strength: 1, Radius: 2}, false},
+	}
+	for _, tt := range tests {
+		c := canvas.NewCanvas(16, 16)
+		c.EnableHDR(nil, 1)
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				c.PutPixelHDR(x, y, white)
+			}
+		}
+		tt.vignette.Apply(c)
+
+		if center := c.ColorAt(8, 8); center != white {
+			t.Errorf("%s: center = %v, want %v", tt.name, center, white)
+		}
+		corner := c.ColorAt(0, 0).X
+		if tt.darkened && (corner < 0 || corner > 0.5) || !tt.darkened && corner != 1 {
+			t.Errorf("%s: corner = %v, want darkened %v", tt.name, corner, tt.darkened)
+		}
+	}
+}
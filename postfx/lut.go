@@ -0,0 +1,155 @@
+package postfx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// LUT is a three-dimensional lookup table that maps linear RGB input colors to
+// output colors. Colors between the entries of the table are interpolated.
+type LUT struct {
+	Size int
+	// Entries are ordered with red changing fastest, then green, then blue.
+	Entries []geom.Vec3
+}
+
+// IdentityLUT returns a LUT of the given size that leaves colors unchanged.
+// Sizes smaller than 2 cannot span the range of colors, so are treated as 2.
+func IdentityLUT(size int) *LUT {
+	if size < 2 {
+		size = 2
+	}
+	lut := &LUT{Size: size, Entries: make([]geom.Vec3, 0, size*size*size)}
+	step := 1 / float32(size-1)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				lut.Entries = append(lut.Entries, geom.Vec3{
+					X: float32(r) * step,
+					Y: float32(g) * step,
+					Z: float32(b) * step,
+				})
+			}
+		}
+	}
+	return lut
+}
+
+// ReadCubeLUT reads a LUT in the Adobe .cube format.
+func ReadCubeLUT(r io.Reader) (*LUT, error) {
+	lut := &LUT{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "LUT_3D_SIZE":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: LUT_3D_SIZE takes one value", lineNum)
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil || size < 2 {
+				return nil, fmt.Errorf("line %d: invalid LUT size %q", lineNum, fields[1])
+			}
+			lut.Size = size
+		case "TITLE":
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			// We only support the default domain of [0, 1]
+			want := 0.0
+			if fields[0] == "DOMAIN_MAX" {
+				want = 1
+			}
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("line %d: %s takes three values", lineNum, fields[0])
+			}
+			for _, field := range fields[1:] {
+				value, err := strconv.ParseFloat(field, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNum, err)
+				}
+				if value != want {
+					return nil, fmt.Errorf("line %d: unsupported %s %q, only %v is supported", lineNum, fields[0], field, want)
+				}
+			}
+		default:
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("line %d: expected three values", lineNum)
+			}
+			var rgb [3]float32
+			for i, field := range fields {
+				value, err := strconv.ParseFloat(field, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNum, err)
+				}
+				rgb[i] = float32(value)
+			}
+			lut.Entries = append(lut.Entries, geom.Vec3{X: rgb[0], Y: rgb[1], Z: rgb[2]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lut.Size == 0 {
+		return nil, fmt.Errorf("missing LUT_3D_SIZE")
+	}
+	if len(lut.Entries) != lut.Size*lut.Size*lut.Size {
+		return nil, fmt.Errorf("expected %d entries, found %d", lut.Size*lut.Size*lut.Size, len(lut.Entries))
+	}
+	return lut, nil
+}
+
+// Lookup returns the color that clr maps to, using trilinear interpolation.
+// The components of clr are clamped to between 0 and 1.
+func (lut *LUT) Lookup(clr geom.Vec3) geom.Vec3 {
+	scale := float32(lut.Size - 1)
+	r0, r1, rt := lut.cell(clamp01(clr.X) * scale)
+	g0, g1, gt := lut.cell(clamp01(clr.Y) * scale)
+	b0, b1, bt := lut.cell(clamp01(clr.Z) * scale)
+
+	lerpR := func(g, b int) geom.Vec3 {
+		return lut.entry(r0, g, b).InterpolateTo(lut.entry(r1, g, b), rt)
+	}
+	lerpG := func(b int) geom.Vec3 {
+		return lerpR(g0, b).InterpolateTo(lerpR(g1, b), gt)
+	}
+	return lerpG(b0).InterpolateTo(lerpG(b1), bt)
+}
+
+// cell returns the indices of the entries either side of x, and how far x is
+// between them.
+func (lut *LUT) cell(x float32) (int, int, float32) {
+	i0 := int(x)
+	if i0 >= lut.Size-1 {
+		return lut.Size - 1, lut.Size - 1, 0
+	}
+	return i0, i0 + 1, x - float32(i0)
+}
+
+func (lut *LUT) entry(r, g, b int) geom.Vec3 {
+	return lut.Entries[(b*lut.Size+g)*lut.Size+r]
+}
+
+// ColorGrade maps every color of the canvas through a LUT.
+type ColorGrade struct {
+	LUT *LUT
+}
+
+// Apply maps the colors of the canvas.
+func (g *ColorGrade) Apply(c *canvas.Canvas) {
+	w, h := c.Dimensions()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c.PutPixelHDR(x, y, g.LUT.Lookup(c.ColorAt(x, y)))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package postfx
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	geom "rasterizer/geometry"
+)
+
+// approxEqual returns whether each component of u is within 1e-6 of v's.
+func approxEqual(u, v geom.Vec3) bool {
+	d := u.Sub(v)
+	return math.Abs(float64(d.X)) <= 1e-6 && math.Abs(float64(d.Y)) <= 1e-6 && math.Abs(float64(d.Z)) <= 1e-6
+}
+
+func TestIdentityLUT(t *testing.T) {
+	tests := []struct {
+		size int
+		in   geom.Vec3
+		want geom.Vec3
+	}{
+		{5, geom.Vec3{X: 0.3, Y: 0.6, Z: 0.9}, geom.Vec3{X: 0.3, Y: 0.6, Z: 0.9}},
+		{2, geom.Vec3{X: 0.25, Y: 0.5, Z: 1}, geom.Vec3{X: 0.25, Y: 0.5, Z: 1}},
+		// Sizes below 2 are treated as 2
+		{1, geom.Vec3{X: 0.25, Y: 0.5, Z: 1}, geom.Vec3{X: 0.25, Y: 0.5, Z: 1}},
+		{0, geom.Vec3{X: 0.75}, geom.Vec3{X: 0.75}},
+		// Colors outside the table are clamped
+		{3, geom.Vec3{X: -1, Y: 2, Z: 0.5}, geom.Vec3{Y: 1, Z: 0.5}},
+	}
+	for _, tt := range tests {
+		lut := IdentityLUT(tt.size)
+		if got := lut.Lookup(tt.in); !approxEqual(got, tt.want) {
+			t.Errorf("IdentityLUT(%d).Lookup(%v) = %v, want %v", tt.size, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadCubeLUT(t *testing.T) {
+	// Swaps red and blue
+	lut, err := ReadCubeLUT(strings.NewReader(`TITLE "swap"
+# comment
+LUT_3D_SIZE 2
+DOMAIN_MIN 0 0 0
+DOMAIN_MAX 1 1 1
+0 0 0
+0 0 1
+0 1 0
+0 1 1
+1 0 0
+1 0 1
+1 1 0
+1 1 1
+`))
+	if err != nil {
+		t.Fatalf("ReadCubeLUT() error = %v", err)
+	}
+	in := geom.Vec3{X: 0.25, Y: 0.5, Z: 0.75}
+	if got, want := lut.Lookup(in), (geom.Vec3{X: 0.75, Y: 0.5, Z: 0.25}); !approxEqual(got, want) {
+		t.Errorf("Lookup(%v) = %v, want %v", in, got, want)
+	}
+}
+
+func TestReadCubeLUTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"no size", "0 0 0\n", "missing LUT_3D_SIZE"},
+		{"size 1", "LUT_3D_SIZE 1\n0 0 0\n", `line 1: invalid LUT size "1"`},
+		{"too few entries", "LUT_3D_SIZE 2\n0 0 0\n", "expected 8 entries, found 1"},
+		{"custom domain", "DOMAIN_MAX 2 2 2\n", `line 1: unsupported DOMAIN_MAX "2"`},
+		{"short entry", "LUT_3D_SIZE 2\n0 0\n", "line 2: expected three values"},
+	}
+	for _, tt := range tests {
+		_, err := ReadCubeLUT(strings.NewReader(tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReadCubeLUT() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package postfx contains screen-space effects that are applied to a Canvas
+// after the scene has been drawn.
+package postfx
+
+import (
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Effect is a pass that processes the whole canvas. Effects read and write
+// linear colors, and can also read the depth buffer.
+type Effect interface {
+	Apply(c *canvas.Canvas)
+}
+
+// EffectFunc allows an ordinary function to be used as an Effect.
+type EffectFunc func(c *canvas.Canvas)
+
+// Apply calls f(c).
+func (f EffectFunc) Apply(c *canvas.Canvas) {
+	f(c)
+}
+
+// Chain is an Effect that applies each of its effects in order.
+type Chain []Effect
+
+// Apply applies each effect in the chain to the canvas.
+func (ch Chain) Apply(c *canvas.Canvas) {
+	for _, effect := range ch {
+		effect.Apply(c)
+	}
+}
+
+// frame is a copy of the colors of a canvas, so that effects which read
+// neighbouring pixels are not affected by the pixels they have already written.
+type frame struct {
+	width, height int
+	pix           []geom.Vec3
+}
+
+func newFrame(width, height int) *frame {
+	return &frame{width: width, height: height, pix: make([]geom.Vec3, width*height)}
+}
+
+func snapshot(c *canvas.Canvas) *frame {
+	w, h := c.Dimensions()
+	f := newFrame(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			f.pix[y*w+x] = c.ColorAt(x, y)
+		}
+	}
+	return f
+}
+
+// at returns the color at (x, y), clamping coordinates to the edge of the frame.
+func (f *frame) at(x, y int) geom.Vec3 {
+	return f.pix[clampInt(y, f.height)*f.width+clampInt(x, f.width)]
+}
+
+func (f *frame) set(x, y int, clr geom.Vec3) {
+	f.pix[y*f.width+x] = clr
+}
+
+// writeTo copies the frame's colors to the canvas.
+func (f *frame) writeTo(c *canvas.Canvas) {
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			c.PutPixelHDR(x, y, f.pix[y*f.width+x])
+		}
+	}
+}
+
+// clampInt clamps x to the range [0, n).
+func clampInt(x, n int) int {
+	switch {
+	case x < 0:
+		return 0
+	case x >= n:
+		return n - 1
+	default:
+		return x
+	}
+}
+
+func clamp01(x float32) float32 {
+	switch {
+	case x < 0:
+		return 0
+	case x > 1:
+		return 1
+	default:
+		return x
+	}
+}
+
+// luminance returns the perceived brightness of a linear RGB color.
+func luminance(clr geom.Vec3) float32 {
+	return 0.2126*clr.X + 0.7152*clr.Y + 0.0722*clr.Z
+}
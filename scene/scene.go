@@ -0,0 +1,135 @@
+// Package scene arranges shapes in a hierarchy, where each node is positioned
+// relative to its parent.
+package scene
+
+import (
+	"fmt"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Transform places a node relative to its parent. Points are scaled first, then
+// rotated, and then translated.
+type Transform struct {
+	Translation geom.Vec3
+	Rotation    geom.Quat
+	Scale       geom.Vec3
+}
+
+// NewTransform returns the transform that leaves points unchanged.
+func NewTransform() Transform {
+	return Transform{
+		Rotation: geom.QuatIdentity(),
+		Scale:    geom.Vec3{X: 1, Y: 1, Z: 1},
+	}
+}
+
+// Matrix returns the matrix that applies the transform.
+func (t Transform) Matrix() *geom.Mat4 {
+	return geom.TRS(t.Translation, t.Rotation, t.Scale)
+}
+
+// Rotate turns the transform by theta around the given axis, which is relative
+// to the node's parent.
+func (t *Transform) Rotate(axis geom.Vec3, theta float32) {
+	// Normalize to stop rounding errors accumulating over many rotations
+	t.Rotation = geom.QuatFromAxisAngle(axis, theta).Mul(t.Rotation).Normalize()
+}
+
+// Node is a part of a scene. It may have a shape attached to it, which is
+// drawn with the node's world transform, and any number of children, which are
+// placed relative to it.
+type Node struct {
+	Name      string
+	Transform Transform
+	// The shape drawn at the node, if any.
+	Mesh *canvas.IndexedTriangleList
+	// The surface that the shape is drawn with.
+	Texture canvas.Texture
+
+	parent   *Node
+	children []*Node
+}
+
+// NewNode returns a node with no shape, placed at its parent's origin.
+func NewNode(name string) *Node {
+	return &Node{Name: name, Transform: NewTransform()}
+}
+
+// Parent returns the node that this node is placed relative to, or nil if it is
+// the root of a scene.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the nodes that are placed relative to this node.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// AddChild places child relative to the node, removing it from its previous
+// parent. It panics if child is the node itself or one of its ancestors, as
+// the scene would then loop forever.
+func (n *Node) AddChild(child *Node) {
+	for p := n; p != nil; p = p.parent {
+		if p == child {
+			panic(fmt.Sprintf("scene: cannot add %q as a child of %q, which is itself or a descendant", child.Name, n.Name))
+		}
+	}
+	if child.parent != nil {
+		child.parent.RemoveChild(child)
+	}
+	child.parent = n
+	n.children = append(n.children, child)
+}
+
+// RemoveChild removes child from the node's children, making it the root of its
+// own scene.
+func (n *Node) RemoveChild(child *Node) {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			child.parent = nil
+			return
+		}
+	}
+}
+
+// WorldMatrix returns the transform from the node's space to the space of the
+// root of its scene.
+func (n *Node) WorldMatrix() *geom.Mat4 {
+	world := n.Transform.Matrix()
+	for p := n.parent; p != nil; p = p.parent {
+		world = p.Transform.Matrix().MatMul(world)
+	}
+	return world
+}
+
+// Walk calls fn for the node and each of its descendants, parents before
+// children, along with their transform to the space of this node's parent.
+func (n *Node) Walk(fn func(node *Node, world *geom.Mat4)) {
+	n.walk(geom.Mat4Identity(), fn)
+}
+
+func (n *Node) walk(parentWorld *geom.Mat4, fn func(node *Node, world *geom.Mat4)) {
+	world := parentWorld.MatMul(n.Transform.Matrix())
+	fn(n, world)
+	for _, child := range n.children {
+		child.walk(world, fn)
+	}
+}
+
+// Find returns the first node with the given name among the node and its
+// descendants, or nil if there is none.
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, child := range n.children {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
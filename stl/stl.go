@@ -0,0 +1,237 @@
+// Package stl reads and writes shapes in the STL format, in both its ASCII and
+// binary forms.
+//
+// STL files use a right-handed coordinate system, so shapes are mirrored along
+// the Z-axis to fit the left-handed system used by the rasterizer.
+package stl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// Size in bytes of the header and of each triangle in a binary file.
+const (
+	headerSize   = 80
+	triangleSize = 50
+)
+
+// Load reads the STL file at path.
+func Load(path string) (*canvas.IndexedTriangleList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return list, nil
+}
+
+// Read reads a shape in either form of the STL format. Corners that share a
+// position are merged into a single vertex. STL files have no vertex normals,
+// so the shape has none.
+func Read(r io.Reader) (*canvas.IndexedTriangleList, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Binary files can also start with "solid", so we check whether the size
+	// matches the triangle count first
+	if len(data) >= headerSize+4 {
+		count := binary.LittleEndian.Uint32(data[headerSize:])
+		if uint64(len(data)) == headerSize+4+uint64(count)*triangleSize {
+			return readBinary(data[headerSize+4:], int(count)), nil
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return readASCII(data)
+	}
+	return nil, fmt.Errorf("not an STL file")
+}
+
+func readBinary(data []byte, count int) *canvas.IndexedTriangleList {
+	b := newBuilder()
+	for i := 0; i < count; i++ {
+		// Skip the facet normal, and the attribute count at the end
+		facet := data[i*triangleSize+12:]
+		var corners [3]geom.Vec3
+		for j := range corners {
+			corners[j] = geom.Vec3{
+				X: readFloat(facet[j*12:]),
+				Y: readFloat(facet[j*12+4:]),
+				Z: readFloat(facet[j*12+8:]),
+			}
+		}
+		b.addTriangle(corners)
+	}
+	return b.list
+}
+
+func readFloat(data []byte) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(data))
+}
+
+func readASCII(data []byte) (*canvas.IndexedTriangleList, error) {
+	b := newBuilder()
+	var corners []geom.Vec3
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "facet":
+			corners = corners[:0]
+		case "vertex":
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("line %d: expected 3 values, found %d", lineNum, len(fields)-1)
+			}
+			var v [3]float32
+			for i, field := range fields[1:] {
+				value, err := strconv.ParseFloat(field, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid number %q", lineNum, field)
+				}
+				v[i] = float32(value)
+			}
+			corners = append(corners, geom.Vec3{X: v[0], Y: v[1], Z: v[2]})
+		case "endfacet":
+			if len(corners) != 3 {
+				return nil, fmt.Errorf("line %d: facet needs 3 vertices, found %d", lineNum, len(corners))
+			}
+			b.addTriangle([3]geom.Vec3{corners[0], corners[1], corners[2]})
+		default:
+			// The solid's name, facet normals and loop markers carry nothing that
+			// we need
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b.list, nil
+}
+
+// builder collects the triangles of a file into a shape, merging corners that
+// share a position.
+type builder struct {
+	list     *canvas.IndexedTriangleList
+	vertices map[geom.Vec3]int
+}
+
+func newBuilder() *builder {
+	return &builder{
+		list:     &canvas.IndexedTriangleList{Topology: canvas.TriangleList},
+		vertices: make(map[geom.Vec3]int),
+	}
+}
+
+// addTriangle adds a triangle with the given corners, as read from the file.
+func (b *builder) addTriangle(corners [3]geom.Vec3) {
+	// Mirroring the Z-axis reverses the winding of the triangle, which we undo
+	// by reversing the order of its vertices
+	for _, corner := range [3]geom.Vec3{corners[0], corners[2], corners[1]} {
+		pos := geom.Vec3{X: corner.X, Y: corner.Y, Z: -corner.Z}
+		index, ok := b.vertices[pos]
+		if !ok {
+			index = len(b.list.Vertices)
+			b.list.Vertices = append(b.list.Vertices, pos)
+			b.vertices[pos] = index
+		}
+		b.list.Indices = append(b.list.Indices, index)
+	}
+}
+
+// Write writes the triangles of a shape in the binary STL format. Points and
+// lines are left out.
+func Write(w io.Writer, list *canvas.IndexedTriangleList) error {
+	facets := facets(list)
+	buf := make([]byte, headerSize+4, headerSize+4+len(facets)*triangleSize)
+	binary.LittleEndian.PutUint32(buf[headerSize:], uint32(len(facets)))
+	for _, f := range facets {
+		for _, v := range [4]geom.Vec3{f.normal, f.corners[0], f.corners[1], f.corners[2]} {
+			buf = appendFloat(buf, v.X)
+			buf = appendFloat(buf, v.Y)
+			buf = appendFloat(buf, v.Z)
+		}
+		// Attribute byte count, which is unused
+		buf = append(buf, 0, 0)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendFloat(buf []byte, x float32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(x))
+	return append(buf, b[:]...)
+}
+
+// WriteASCII writes the triangles of a shape in the ASCII STL format, as a
+// solid with the given name. Points and lines are left out.
+func WriteASCII(w io.Writer, list *canvas.IndexedTriangleList, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "solid %s\n", name)
+	for _, f := range facets(list) {
+		fmt.Fprintf(bw, "  facet normal %s\n", formatVec3(f.normal))
+		fmt.Fprintf(bw, "    outer loop\n")
+		for _, corner := range f.corners {
+			fmt.Fprintf(bw, "      vertex %s\n", formatVec3(corner))
+		}
+		fmt.Fprintf(bw, "    endloop\n")
+		fmt.Fprintf(bw, "  endfacet\n")
+	}
+	fmt.Fprintf(bw, "endsolid %s\n", name)
+	return bw.Flush()
+}
+
+func formatVec3(v geom.Vec3) string {
+	return fmt.Sprintf("%s %s %s", formatFloat(v.X), formatFloat(v.Y), formatFloat(v.Z))
+}
+
+func formatFloat(x float32) string {
+	return strconv.FormatFloat(float64(x), 'g', -1, 32)
+}
+
+// facet is a triangle as it is written to a file.
+type facet struct {
+	normal  geom.Vec3
+	corners [3]geom.Vec3
+}
+
+// facets returns the triangles of a shape mirrored back along the Z-axis, with
+// their normals.
+func facets(list *canvas.IndexedTriangleList) []facet {
+	triangles := list.Triangles()
+	facets := make([]facet, len(triangles))
+	for i, tri := range triangles {
+		var f facet
+		for j, index := range [3]int{tri[0], tri[2], tri[1]} {
+			v := list.Vertices[index]
+			f.corners[j] = geom.Vec3{X: v.X, Y: v.Y, Z: -v.Z}
+		}
+		normal := f.corners[1].Sub(f.corners[0]).Cross(f.corners[2].Sub(f.corners[0]))
+		if normal.LengthSquared() > 0 {
+			f.normal = normal.Normalize()
+		}
+		facets[i] = f
+	}
+	return facets
+}
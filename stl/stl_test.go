@@ -0,0 +1,74 @@
+package stl
+
+import (
+	"bytes"
+	"testing"
+
+	"rasterizer/canvas"
+	geom "rasterizer/geometry"
+)
+
+// tetrahedron returns a closed shape whose triangles share vertices.
+func tetrahedron() *canvas.IndexedTriangleList {
+	return &canvas.IndexedTriangleList{
+		Vertices: []geom.Vec3{{}, {X: 1}, {Y: 1}, {Z: 1.5}},
+		Indices:  []int{0, 2, 1, 0, 1, 3, 0, 3, 2, 1, 2, 3},
+		Topology: canvas.TriangleList,
+	}
+}
+
+// corners returns the positions of the corners of each triangle.
+func corners(list *canvas.IndexedTriangleList) [][3]geom.Vec3 {
+	var tris [][3]geom.Vec3
+	for _, tri := range list.Triangles() {
+		tris = append(tris, [3]geom.Vec3{list.Vertices[tri[0]], list.Vertices[tri[1]], list.Vertices[tri[2]]})
+	}
+	return tris
+}
+
+func TestRoundTrip(t *testing.T) {
+	strip := &canvas.IndexedTriangleList{
+		Vertices: []geom.Vec3{{}, {Y: 1}, {X: 1}, {X: 1, Y: 1, Z: 0.25}},
+		Indices:  []int{0, 1, 2, 3},
+		Topology: canvas.TriangleStrip,
+	}
+	writers := []struct {
+		name  string
+		write func(*bytes.Buffer, *canvas.IndexedTriangleList) error
+	}{
+		{"binary", func(b *bytes.Buffer, l *canvas.IndexedTriangleList) error { return Write(b, l) }},
+		{"ascii", func(b *bytes.Buffer, l *canvas.IndexedTriangleList) error { return WriteASCII(b, l, "test") }},
+	}
+	shapes := []struct {
+		name string
+		list *canvas.IndexedTriangleList
+	}{
+		{"tetrahedron", tetrahedron()},
+		{"strip", strip},
+	}
+	for _, w := range writers {
+		for _, s := range shapes {
+			var buf bytes.Buffer
+			if err := w.write(&buf, s.list); err != nil {
+				t.Fatalf("%s %s: write: %v", w.name, s.name, err)
+			}
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("%s %s: read: %v", w.name, s.name, err)
+			}
+			if len(got.Vertices) != len(s.list.Vertices) {
+				t.Errorf("%s %s: read %d vertices, want %d", w.name, s.name, len(got.Vertices), len(s.list.Vertices))
+			}
+			gotCorners, wantCorners := corners(got), corners(s.list)
+			if len(gotCorners) != len(wantCorners) {
+				t.Errorf("%s %s: read %d triangles, want %d", w.name, s.name, len(gotCorners), len(wantCorners))
+				continue
+			}
+			for i := range wantCorners {
+				if gotCorners[i] != wantCorners[i] {
+					t.Errorf("%s %s: triangle %d = %v, want %v", w.name, s.name, i, gotCorners[i], wantCorners[i])
+				}
+			}
+		}
+	}
+}